@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+// Alt text policies for media without alt text.
+const (
+	altTextPolicyDefault = "default" // Fall back to a generic alt text such as "Image 1".
+	altTextPolicyWarn    = "warn"    // Fall back to a generic alt text and log a warning.
+	altTextPolicyRequire = "require" // Fail the action.
+)
+
+// Maximum alt text lengths allowed by the Bluesky lexicons.
+const (
+	maxImageAltTextGraphemes = 2000
+	maxVideoAltTextGraphemes = 1000
+)
+
+// zeroWidthJoiner joins emoji into a single grapheme cluster.
+const zeroWidthJoiner = '\u200d'
+
+// AltTextPolicy controls how missing and overlong alt texts are handled.
+type AltTextPolicy struct {
+	Mode        string // require, warn, or default.
+	CheckLength bool   // Validate alt texts against the maximum length of the media kind.
+}
+
+// isGraphemeExtender reports whether a rune continues the preceding grapheme
+// cluster rather than starting a new one.
+func isGraphemeExtender(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zeroWidthJoiner:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // Variation selectors.
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // Emoji skin tone modifiers.
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // Emoji tag sequences.
+		return true
+	default:
+		return false
+	}
+}
+
+// isRegionalIndicator reports whether a rune is half of a flag emoji.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// graphemeStarts returns the byte offsets at which the user-perceived
+// characters of a string start, treating combining marks, emoji sequences,
+// and flags as one.
+func graphemeStarts(s string) []int {
+	var starts []int
+	prev := rune(-1)
+	pendingFlag := false
+
+	for i, r := range s {
+		switch {
+		case prev == zeroWidthJoiner, isGraphemeExtender(r):
+			// Part of the previous cluster.
+		case prev == '\r' && r == '\n':
+			// CRLF is a single cluster.
+		case pendingFlag && isRegionalIndicator(r):
+			pendingFlag = false
+		default:
+			starts = append(starts, i)
+			pendingFlag = isRegionalIndicator(r)
+		}
+		prev = r
+	}
+
+	return starts
+}
+
+// graphemeCount approximates the number of user-perceived characters in a
+// string.
+func graphemeCount(s string) int {
+	return len(graphemeStarts(s))
+}
+
+// truncateGraphemes shortens a string to at most limit user-perceived
+// characters, ending it with an ellipsis when it is cut.
+func truncateGraphemes(s string, limit int) string {
+	starts := graphemeStarts(s)
+	if len(starts) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+	return strings.TrimRightFunc(s[:starts[limit-1]], unicode.IsSpace) + "…"
+}
+
+// applyAltTextPolicy validates the alt text of a media file against the policy
+// and the maximum length of its media kind, and returns the alt text to use.
+// Missing alt text is replaced by fallback unless the policy requires alt text.
+func applyAltTextPolicy(alt, fallback, path string, maxGraphemes int, policy AltTextPolicy, logger *slog.Logger) (string, error) {
+	alt = strings.TrimSpace(alt)
+
+	switch policy.Mode {
+	case "", altTextPolicyDefault, altTextPolicyWarn, altTextPolicyRequire:
+	default:
+		return "", fmt.Errorf("unknown alt text policy %q (supported: require, warn, default)", policy.Mode)
+	}
+
+	if alt == "" {
+		switch policy.Mode {
+		case altTextPolicyRequire:
+			return "", fmt.Errorf("missing alt text for %s", path)
+		case altTextPolicyWarn:
+			logger.Warn("Missing alt text, using generic fallback", "path", path, "alt", fallback)
+		}
+		return fallback, nil
+	}
+
+	if policy.CheckLength {
+		if length := graphemeCount(alt); length > maxGraphemes {
+			if policy.Mode == altTextPolicyRequire {
+				return "", fmt.Errorf("alt text for %s is %d graphemes long, maximum is %d", path, length, maxGraphemes)
+			}
+			logger.Warn("Alt text exceeds maximum length", "path", path, "graphemes", length, "max", maxGraphemes)
+		}
+	}
+
+	return alt, nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "empty", input: "", want: 0},
+		{name: "ASCII", input: "Build passed", want: 12},
+		{name: "combining accent", input: "cafe\u0301", want: 4},
+		{name: "emoji with skin tone", input: "\U0001F44D\U0001F3FD", want: 1},
+		{name: "ZWJ family emoji", input: "\U0001F468\u200d\U0001F469\u200d\U0001F467", want: 1},
+		{name: "emoji with variation selector", input: "\u2764\ufe0f", want: 1},
+		{name: "two flags", input: "\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7", want: 2},
+		{name: "CRLF", input: "a\r\nb", want: 3},
+		{name: "CJK", input: "日本語", want: 3},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := graphemeCount(tc.input); got != tc.want {
+				t.Errorf("graphemeCount(%q) = %d, want %d", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTruncateGraphemes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		limit int
+		want  string
+	}{
+		{name: "within limit", input: "Build passed", limit: 12, want: "Build passed"},
+		{name: "cut with ellipsis", input: "Build passed", limit: 6, want: "Build…"},
+		{name: "keeps emoji clusters whole", input: "ok \U0001F44D\U0001F3FD\U0001F44D!", limit: 5, want: "ok \U0001F44D\U0001F3FD…"},
+		{name: "combining accent", input: "cafe\u0301s", limit: 4, want: "caf…"},
+		{name: "zero limit", input: "Build", limit: 0, want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := truncateGraphemes(tc.input, tc.limit); got != tc.want {
+				t.Errorf("truncateGraphemes(%q, %d) = %q, want %q", tc.input, tc.limit, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyAltTextPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	overlong := strings.Repeat("\U0001F44D\U0001F3FD", maxImageAltTextGraphemes) + "!"
+
+	tests := []struct {
+		name         string
+		alt          string
+		maxGraphemes int
+		policy       AltTextPolicy
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:   "alt text given",
+			alt:    "  Chart of build times  ",
+			policy: AltTextPolicy{Mode: altTextPolicyRequire},
+			want:   "Chart of build times",
+		},
+		{
+			name:   "missing alt with empty policy falls back",
+			alt:    "",
+			policy: AltTextPolicy{},
+			want:   "Image 1",
+		},
+		{
+			name:   "missing alt with default policy falls back",
+			alt:    "   ",
+			policy: AltTextPolicy{Mode: altTextPolicyDefault},
+			want:   "Image 1",
+		},
+		{
+			name:   "missing alt with warn policy falls back",
+			alt:    "",
+			policy: AltTextPolicy{Mode: altTextPolicyWarn},
+			want:   "Image 1",
+		},
+		{
+			name:    "missing alt with require policy",
+			alt:     "",
+			policy:  AltTextPolicy{Mode: altTextPolicyRequire},
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			alt:     "Chart",
+			policy:  AltTextPolicy{Mode: "strict"},
+			wantErr: true,
+		},
+		{
+			name:   "exactly at the length limit",
+			alt:    strings.Repeat("\U0001F44D\U0001F3FD", maxImageAltTextGraphemes),
+			policy: AltTextPolicy{Mode: altTextPolicyRequire, CheckLength: true},
+			want:   strings.Repeat("\U0001F44D\U0001F3FD", maxImageAltTextGraphemes),
+		},
+		{
+			name:    "overlong alt with require policy",
+			alt:     overlong,
+			policy:  AltTextPolicy{Mode: altTextPolicyRequire, CheckLength: true},
+			wantErr: true,
+		},
+		{
+			name:   "overlong alt with warn policy is kept",
+			alt:    overlong,
+			policy: AltTextPolicy{Mode: altTextPolicyWarn, CheckLength: true},
+			want:   overlong,
+		},
+		{
+			name:         "video alt over the video limit",
+			alt:          strings.Repeat("a", maxVideoAltTextGraphemes+1),
+			maxGraphemes: maxVideoAltTextGraphemes,
+			policy:       AltTextPolicy{Mode: altTextPolicyRequire, CheckLength: true},
+			wantErr:      true,
+		},
+		{
+			name:   "overlong alt without length check",
+			alt:    overlong,
+			policy: AltTextPolicy{Mode: altTextPolicyRequire},
+			want:   overlong,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			maxGraphemes := tc.maxGraphemes
+			if maxGraphemes == 0 {
+				maxGraphemes = maxImageAltTextGraphemes
+			}
+
+			got, err := applyAltTextPolicy(tc.alt, "Image 1", "image.png", maxGraphemes, tc.policy, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applyAltTextPolicy() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("applyAltTextPolicy() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessImagesAltTextPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	first := filepath.Join(tempDir, "first.png")
+	second := filepath.Join(tempDir, "second.png")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, fakeImageData(pngMagic, 64), 0644); err != nil {
+			t.Fatalf("Failed to write test image: %v", err)
+		}
+	}
+
+	uploads := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]interface{}{"$link": "bafkreialt"},
+				"mimeType": "image/png",
+				"size":     64,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	t.Run("require fails before uploading", func(t *testing.T) {
+		uploads = 0
+		opts := ImageOptions{AltText: AltTextPolicy{Mode: altTextPolicyRequire}}
+		_, err := processImages(mockServer.URL, "fake-token", first+","+second, "First,", opts, logger)
+		if err == nil || !strings.Contains(err.Error(), "second.png") {
+			t.Fatalf("processImages() error = %v, want missing alt text error for second.png", err)
+		}
+		if uploads != 0 {
+			t.Errorf("processImages() uploaded %d images, want 0", uploads)
+		}
+	})
+
+	t.Run("default falls back to generic alt", func(t *testing.T) {
+		opts := ImageOptions{AltText: AltTextPolicy{Mode: altTextPolicyDefault}}
+		result, err := processImages(mockServer.URL, "fake-token", first+","+second, "First,", opts, logger)
+		if err != nil {
+			t.Fatalf("processImages() unexpected error = %v", err)
+		}
+		if result.Images[0].Alt != "First" || result.Images[1].Alt != "Image 2" {
+			t.Errorf("processImages() alts = %q, %q, want %q, %q", result.Images[0].Alt, result.Images[1].Alt, "First", "Image 2")
+		}
+	})
+}
+
+func TestProcessVideosAltTextPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	_, err := processVideos("https://test.pds", "token", "did:plc:test", "demo.mp4", "", VideoOptions{AltText: AltTextPolicy{Mode: altTextPolicyRequire}}, logger)
+	if err == nil || !strings.Contains(err.Error(), "missing alt text") {
+		t.Errorf("processVideos() error = %v, want missing alt text error", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Constants for video caption constraints of the app.bsky.embed.video lexicon.
+const (
+	maxCaptionSize   = 20000 // Bytes per caption file.
+	maxCaptionTracks = 20
+)
+
+// captionLangPattern matches BCP 47 language tags such as "en", "pt-BR", or "zh-Hant".
+var captionLangPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// captionFile is a validated WebVTT caption file ready for upload.
+type captionFile struct {
+	Lang string
+	Path string
+	Data []byte
+}
+
+// captionSpec is a caption language and the path or URL of its file.
+type captionSpec struct {
+	Lang string
+	Path string
+}
+
+// parseCaptionSpecs splits comma-separated "lang=path" pairs into caption specs.
+func parseCaptionSpecs(spec string) ([]captionSpec, error) {
+	var specs []captionSpec
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		lang, path, ok := strings.Cut(entry, "=")
+		lang, path = strings.TrimSpace(lang), strings.TrimSpace(path)
+		if !ok || lang == "" || path == "" {
+			return nil, fmt.Errorf("invalid caption %q, expected lang=path", entry)
+		}
+		if !captionLangPattern.MatchString(lang) {
+			return nil, fmt.Errorf("invalid caption language %q, expected a language code such as en or pt-BR", lang)
+		}
+		if seen[strings.ToLower(lang)] {
+			return nil, fmt.Errorf("duplicate caption language %s", lang)
+		}
+		seen[strings.ToLower(lang)] = true
+
+		specs = append(specs, captionSpec{Lang: lang, Path: path})
+	}
+
+	if len(specs) > maxCaptionTracks {
+		return nil, fmt.Errorf("maximum %d caption tracks allowed per video, got %d", maxCaptionTracks, len(specs))
+	}
+
+	return specs, nil
+}
+
+// validateWebVTT checks that caption data is a UTF-8 WebVTT file within the size limit.
+func validateWebVTT(path string, data []byte) error {
+	if len(data) > maxCaptionSize {
+		return fmt.Errorf("caption file %s exceeds maximum size of %d bytes (got %d bytes)", path, maxCaptionSize, len(data))
+	}
+
+	if !utf8.Valid(data) {
+		return fmt.Errorf("caption file %s is not valid UTF-8", path)
+	}
+
+	// The signature may follow a byte order mark and must end the first line or be followed by a space or tab.
+	content := bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !bytes.HasPrefix(content, []byte("WEBVTT")) {
+		return fmt.Errorf("caption file %s is not a WebVTT file (missing WEBVTT header)", path)
+	}
+	if rest := content[len("WEBVTT"):]; len(rest) > 0 && !strings.ContainsRune(" \t\r\n", rune(rest[0])) {
+		return fmt.Errorf("caption file %s is not a WebVTT file (invalid WEBVTT header)", path)
+	}
+
+	return nil
+}
+
+// loadCaptions reads and validates the caption files of the video options.
+func loadCaptions(opts VideoOptions, logger *slog.Logger) ([]captionFile, error) {
+	specs, err := parseCaptionSpecs(opts.Captions)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []captionFile
+	for _, spec := range specs {
+		var data []byte
+		if isRemoteMedia(spec.Path) {
+			data, _, err = downloadMedia(context.Background(), spec.Path, "text", maxCaptionSize, opts.GitHubToken, logger)
+		} else {
+			data, err = os.ReadFile(spec.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read caption file %s: %w", spec.Path, err)
+		}
+
+		if err := validateWebVTT(spec.Path, data); err != nil {
+			return nil, err
+		}
+
+		files = append(files, captionFile{Lang: spec.Lang, Path: spec.Path, Data: data})
+	}
+
+	return files, nil
+}
+
+// uploadCaptions uploads caption files as text/vtt blobs and returns the video captions.
+func uploadCaptions(pdsURL, accessToken string, files []captionFile, logger *slog.Logger) ([]Caption, error) {
+	var captions []Caption
+
+	for _, file := range files {
+		logger.Debug("Uploading caption file", "lang", file.Lang, "path", file.Path, "size", len(file.Data))
+
+		blob, err := uploadBlob(pdsURL, accessToken, file.Data, "text/vtt", logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload caption file %s: %w", file.Path, err)
+		}
+
+		captions = append(captions, Caption{Lang: file.Lang, File: *blob})
+	}
+
+	return captions, nil
+}
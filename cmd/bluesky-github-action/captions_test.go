@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWebVTT = "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nHello, Bluesky!\n"
+
+func TestParseCaptionSpecs(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []captionSpec
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			spec: "",
+			want: nil,
+		},
+		{
+			name: "multiple languages",
+			spec: "en=captions/en.vtt, pt-BR = captions/pt.vtt,",
+			want: []captionSpec{
+				{Lang: "en", Path: "captions/en.vtt"},
+				{Lang: "pt-BR", Path: "captions/pt.vtt"},
+			},
+		},
+		{
+			name: "URL with query string",
+			spec: "de=https://example.com/de.vtt?raw=1",
+			want: []captionSpec{
+				{Lang: "de", Path: "https://example.com/de.vtt?raw=1"},
+			},
+		},
+		{
+			name:    "missing language",
+			spec:    "captions/en.vtt",
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			spec:    "en=",
+			wantErr: true,
+		},
+		{
+			name:    "invalid language",
+			spec:    "english subtitles=en.vtt",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate language",
+			spec:    "en=a.vtt,EN=b.vtt",
+			wantErr: true,
+		},
+		{
+			name:    "too many tracks",
+			spec:    tooManyCaptionSpecs(),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseCaptionSpecs(tc.spec)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseCaptionSpecs() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("parseCaptionSpecs() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("parseCaptionSpecs()[%d] = %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// tooManyCaptionSpecs returns one more caption spec than allowed.
+func tooManyCaptionSpecs() string {
+	var specs []string
+	for i := 0; i <= maxCaptionTracks; i++ {
+		specs = append(specs, string(rune('a'+i/26))+string(rune('a'+i%26))+"=captions.vtt")
+	}
+	return strings.Join(specs, ",")
+}
+
+func TestValidateWebVTT(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: testWebVTT},
+		{name: "header only", data: "WEBVTT"},
+		{name: "header with title", data: "WEBVTT - Release demo\n\n"},
+		{name: "byte order mark", data: "\xEF\xBB\xBF" + testWebVTT},
+		{name: "CRLF line endings", data: "WEBVTT\r\n\r\n00:00.000 --> 00:01.000\r\nHi\r\n"},
+		{name: "SubRip file", data: "1\n00:00:00,000 --> 00:00:02,000\nHello\n", wantErr: true},
+		{name: "header without separator", data: "WEBVTTX\n", wantErr: true},
+		{name: "invalid UTF-8", data: "WEBVTT\n\n\xff\xfe", wantErr: true},
+		{name: "too large", data: "WEBVTT\n\n" + strings.Repeat("a", maxCaptionSize), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateWebVTT("captions.vtt", []byte(tc.data))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateWebVTT() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessVideoCaptions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "video-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	videoPath := filepath.Join(tempDir, "demo.mp4")
+	if err := os.WriteFile(videoPath, testVideo(30*time.Second), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+	for name, content := range map[string]string{
+		"en.vtt":  testWebVTT,
+		"de.vtt":  "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nHallo, Bluesky!\n",
+		"bad.srt": "1\n00:00:00,000 --> 00:00:02,000\nHello\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write caption file: %v", err)
+		}
+	}
+
+	var uploadedCaptions []string
+	videoUploaded := false
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getServiceAuth"):
+			json.NewEncoder(w).Encode(ServiceAuthResponse{Token: "service-token"})
+		case strings.Contains(r.URL.Path, "uploadBlob"):
+			if r.Header.Get("Content-Type") != "text/vtt" {
+				t.Errorf("caption Content-Type = %s, want text/vtt", r.Header.Get("Content-Type"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			uploadedCaptions = append(uploadedCaptions, string(body))
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"blob": Blob{Type: "blob", Ref: BlobRef{Link: "bafkreicaption"}, MimeType: "text/vtt", Size: len(body)},
+			})
+		}
+	}))
+	defer pdsServer.Close()
+
+	videoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getUploadLimits"):
+			w.Write([]byte(`{"canUpload": true}`))
+		case strings.Contains(r.URL.Path, "uploadVideo"):
+			videoUploaded = true
+			json.NewEncoder(w).Encode(VideoUploadResponse{
+				JobID: "job123",
+				Status: &VideoJobStatus{
+					Blob: &Blob{Type: "blob", Ref: BlobRef{Link: "bafkreivideo"}, MimeType: "video/mp4", Size: 1024},
+				},
+			})
+		}
+	}))
+	defer videoServer.Close()
+	useVideoService(t, videoServer.URL)
+
+	t.Run("captions attached", func(t *testing.T) {
+		uploadedCaptions = nil
+		opts := VideoOptions{Captions: "en=" + filepath.Join(tempDir, "en.vtt") + ",de=" + filepath.Join(tempDir, "de.vtt")}
+
+		result, err := processVideo(pdsServer.URL, "access-token", "did:plc:test", videoPath, "Demo", opts, logger)
+		if err != nil {
+			t.Fatalf("processVideo() unexpected error = %v", err)
+		}
+
+		if len(result.Captions) != 2 || result.Captions[0].Lang != "en" || result.Captions[1].Lang != "de" {
+			t.Fatalf("processVideo() captions = %+v, want en and de", result.Captions)
+		}
+		if result.Captions[0].File.MimeType != "text/vtt" {
+			t.Errorf("caption blob mimeType = %s, want text/vtt", result.Captions[0].File.MimeType)
+		}
+		if len(uploadedCaptions) != 2 || uploadedCaptions[0] != testWebVTT {
+			t.Errorf("uploaded captions = %q, want en and de files", uploadedCaptions)
+		}
+	})
+
+	t.Run("invalid caption fails before video upload", func(t *testing.T) {
+		videoUploaded = false
+		opts := VideoOptions{Captions: "en=" + filepath.Join(tempDir, "bad.srt")}
+
+		_, err := processVideo(pdsServer.URL, "access-token", "did:plc:test", videoPath, "Demo", opts, logger)
+		if err == nil || !strings.Contains(err.Error(), "WebVTT") {
+			t.Fatalf("processVideo() error = %v, want WebVTT validation error", err)
+		}
+		if videoUploaded {
+			t.Error("video uploaded despite invalid caption file")
+		}
+	})
+}
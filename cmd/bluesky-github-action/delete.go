@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// postCollection is the collection of Bluesky post records.
+const postCollection = "app.bsky.feed.post"
+
+// PostURI identifies a post record by the repository and record key of its
+// AT-URI, at://<repo>/app.bsky.feed.post/<rkey>.
+type PostURI struct {
+	Repo string // DID or handle of the repository.
+	RKey string // Record key.
+}
+
+// parsePostURI parses the AT-URI of a post.
+func parsePostURI(uri string) (PostURI, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(uri), "at://")
+	if !ok {
+		return PostURI{}, fmt.Errorf("invalid post URI %q, expected at://<did>/%s/<rkey>", uri, postCollection)
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return PostURI{}, fmt.Errorf("invalid post URI %q, expected at://<did>/%s/<rkey>", uri, postCollection)
+	}
+	if parts[1] != postCollection {
+		return PostURI{}, fmt.Errorf("URI %q is not a post, expected collection %s", uri, postCollection)
+	}
+
+	return PostURI{Repo: parts[0], RKey: parts[2]}, nil
+}
+
+// isOwnRepo reports whether the repository of an AT-URI, given as a DID or a
+// handle, is the repository of the authenticated account.
+func isOwnRepo(repo string, session *SessionResponse) bool {
+	if strings.HasPrefix(repo, "did:") {
+		return repo == session.UserID
+	}
+	return session.Handle != "" && strings.EqualFold(repo, session.Handle)
+}
+
+// deletePost deletes a post of the authenticated account. Posts of other
+// accounts are rejected before any request is sent.
+// nolint: errcheck
+func deletePost(pdsURL string, session *SessionResponse, uri string, logger *slog.Logger) error {
+	post, err := parsePostURI(uri)
+	if err != nil {
+		return err
+	}
+	if !isOwnRepo(post.Repo, session) {
+		return fmt.Errorf("post %s does not belong to the authenticated account %s", uri, session.UserID)
+	}
+
+	deleteURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.deleteRecord", pdsURL)
+	deleteData, err := json.Marshal(map[string]string{
+		"repo":       session.UserID,
+		"collection": postCollection,
+		"rkey":       post.RKey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	request, err := http.NewRequest("POST", deleteURL, bytes.NewBuffer(deleteData))
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+session.AccessToken)
+
+	logger.Debug("Deleting post", "uri", uri)
+
+	client := &http.Client{}
+	resp, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send delete request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete post, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// runDelete deletes the post given in the inputs and reports it in the step
+// outputs.
+func runDelete(args ActionInputs, logger *slog.Logger) error {
+	logger.Info("Starting session creation")
+	session, err := createSession(args.PDSURL, args.Handle, args.Password)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if err := deletePost(args.PDSURL, session, args.DeleteURI, logger); err != nil {
+		return err
+	}
+	logger.Info("Post deleted successfully", "uri", args.DeleteURI)
+
+	setOutputs(map[string]string{
+		"deleted": "true",
+		"success": "true",
+	}, logger)
+	return nil
+}
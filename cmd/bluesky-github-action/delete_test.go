@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePostURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    PostURI
+		wantErr bool
+	}{
+		{name: "DID repository", uri: "at://did:plc:abc123/app.bsky.feed.post/3kxyz", want: PostURI{Repo: "did:plc:abc123", RKey: "3kxyz"}},
+		{name: "handle repository", uri: " at://alice.bsky.social/app.bsky.feed.post/3kxyz\n", want: PostURI{Repo: "alice.bsky.social", RKey: "3kxyz"}},
+		{name: "web URL", uri: "https://bsky.app/profile/alice.bsky.social/post/3kxyz", wantErr: true},
+		{name: "other collection", uri: "at://did:plc:abc123/app.bsky.feed.like/3kxyz", wantErr: true},
+		{name: "missing record key", uri: "at://did:plc:abc123/app.bsky.feed.post/", wantErr: true},
+		{name: "extra segments", uri: "at://did:plc:abc123/app.bsky.feed.post/3kxyz/extra", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parsePostURI(tc.uri)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parsePostURI() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("parsePostURI() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:abc123", Handle: "alice.bsky.social"}
+
+	tests := []struct {
+		name           string
+		uri            string
+		mockStatusCode int
+		wantRequest    bool
+		wantErr        bool
+	}{
+		{name: "own post", uri: "at://did:plc:abc123/app.bsky.feed.post/3kxyz", mockStatusCode: http.StatusOK, wantRequest: true},
+		{name: "own post by handle", uri: "at://Alice.bsky.social/app.bsky.feed.post/3kxyz", mockStatusCode: http.StatusOK, wantRequest: true},
+		{name: "post of another account", uri: "at://did:plc:other/app.bsky.feed.post/3kxyz", wantErr: true},
+		{name: "post of another handle", uri: "at://bob.bsky.social/app.bsky.feed.post/3kxyz", wantErr: true},
+		{name: "invalid URI", uri: "3kxyz", wantErr: true},
+		{name: "server error", uri: "at://did:plc:abc123/app.bsky.feed.post/3kxyz", mockStatusCode: http.StatusBadRequest, wantRequest: true, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var requested bool
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = true
+				if r.URL.Path != "/xrpc/com.atproto.repo.deleteRecord" || r.Header.Get("Authorization") != "Bearer access-token" {
+					t.Errorf("unexpected request %s with Authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+
+				var body map[string]string
+				json.NewDecoder(r.Body).Decode(&body)
+				if body["repo"] != "did:plc:abc123" || body["collection"] != "app.bsky.feed.post" || body["rkey"] != "3kxyz" {
+					t.Errorf("delete request body = %v", body)
+				}
+
+				w.WriteHeader(tc.mockStatusCode)
+				w.Write([]byte(`{}`))
+			}))
+			defer mockServer.Close()
+
+			err := deletePost(mockServer.URL, session, tc.uri, logger)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("deletePost() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if requested != tc.wantRequest {
+				t.Errorf("deleteRecord requested = %v, want %v", requested, tc.wantRequest)
+			}
+		})
+	}
+}
+
+func TestRunDelete(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outputPath := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "createSession"):
+			w.Write([]byte(`{"accessJwt": "access-token", "did": "did:plc:abc123", "handle": "alice.bsky.social"}`))
+		case strings.HasSuffix(r.URL.Path, "deleteRecord"):
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer mockServer.Close()
+
+	args := ActionInputs{PDSURL: mockServer.URL, Handle: "alice.bsky.social", Password: "app-password", DeleteURI: "at://did:plc:abc123/app.bsky.feed.post/3kxyz"}
+	if err := runDelete(args, logger); err != nil {
+		t.Fatalf("runDelete() unexpected error = %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if want := "deleted=true\nsuccess=true\n"; string(content) != want {
+		t.Errorf("output file content = %q, want %q", content, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+)
+
+// forEachBox iterates over the ISO base media boxes in data, calling fn with
+// each box type and payload. Iteration stops when fn returns false.
+func forEachBox(data []byte, fn func(boxType string, payload []byte) bool) {
+	for offset := 0; offset+8 <= len(data); {
+		size := uint64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		boxType := string(data[offset+4 : offset+8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data) - offset)
+		case 1:
+			if offset+16 > len(data) {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[offset+8 : offset+16])
+			headerSize = 16
+		}
+
+		if size < headerSize || size > uint64(len(data)-offset) {
+			return
+		}
+
+		if !fn(boxType, data[offset+int(headerSize):offset+int(size)]) {
+			return
+		}
+		offset += int(size)
+	}
+}
+
+// findBox returns the payload of the first box of the given type in data, or nil.
+func findBox(data []byte, boxType string) []byte {
+	var found []byte
+	forEachBox(data, func(t string, payload []byte) bool {
+		if t == boxType {
+			found = payload
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// webpDimensions reads the canvas size from the first chunk of a WebP file.
+func webpDimensions(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, fmt.Errorf("invalid WebP header")
+	}
+
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8 ":
+		// Lossy: 3-byte frame tag, 3-byte start code, then 14-bit width and height.
+		if chunk[3] != 0x9D || chunk[4] != 0x01 || chunk[5] != 0x2A {
+			return 0, 0, fmt.Errorf("invalid VP8 start code")
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3FFF)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3FFF)
+		return width, height, nil
+	case "VP8L":
+		// Lossless: signature byte, then 14-bit width-1 and height-1.
+		if chunk[0] != 0x2F {
+			return 0, 0, fmt.Errorf("invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		width := int(bits&0x3FFF) + 1
+		height := int((bits>>14)&0x3FFF) + 1
+		return width, height, nil
+	case "VP8X":
+		// Extended: flags and reserved bytes, then 24-bit canvas width-1 and height-1.
+		width := int(uint32(chunk[4])|uint32(chunk[5])<<8|uint32(chunk[6])<<16) + 1
+		height := int(uint32(chunk[7])|uint32(chunk[8])<<8|uint32(chunk[9])<<16) + 1
+		return width, height, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported WebP chunk %q", string(data[12:16]))
+	}
+}
+
+// heifDimensions reads the image size of an AVIF or HEIC file from the item
+// properties in its meta box. The largest image spatial extent is used, since
+// files may also carry thumbnails, and a 90 or 270 degree rotation swaps the sides.
+func heifDimensions(data []byte) (int, int, error) {
+	meta := findBox(data, "meta")
+	if len(meta) < 4 {
+		return 0, 0, fmt.Errorf("no meta box found")
+	}
+
+	// meta is a full box: skip version and flags.
+	ipco := findBox(findBox(meta[4:], "iprp"), "ipco")
+
+	// Extents are compared as uint64, since their area overflows int on 32-bit platforms.
+	var width, height uint64
+	var rotation int
+	forEachBox(ipco, func(boxType string, payload []byte) bool {
+		switch boxType {
+		case "ispe":
+			if len(payload) >= 12 {
+				w := uint64(binary.BigEndian.Uint32(payload[4:8]))
+				h := uint64(binary.BigEndian.Uint32(payload[8:12]))
+				if w <= math.MaxInt32 && h <= math.MaxInt32 && w*h > width*height {
+					width, height = w, h
+				}
+			}
+		case "irot":
+			if len(payload) >= 1 {
+				rotation = int(payload[0] & 0x03)
+			}
+		}
+		return true
+	})
+
+	if width == 0 || height == 0 {
+		return 0, 0, fmt.Errorf("no image spatial extent found")
+	}
+
+	if rotation == 1 || rotation == 3 {
+		width, height = height, width
+	}
+
+	return int(width), int(height), nil
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG image, or 1 if
+// the image has no orientation tag.
+func jpegOrientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return 1
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		// Start of scan: no more metadata segments follow.
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+
+		segment := data[offset+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		offset = end
+	}
+
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of TIFF-formatted EXIF data.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	// Check the offset before converting it, as it overflows int on 32-bit platforms.
+	offset := uint64(order.Uint32(tiff[4:8]))
+	if offset+2 > uint64(len(tiff)) {
+		return 1
+	}
+	ifdOffset := int(offset)
+
+	entries := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// decodeImageDimensions returns the display width and height of an image,
+// accounting for EXIF orientation in JPEG images.
+func decodeImageDimensions(imageData []byte) (int, int, error) {
+	switch sniffImageMimeType(imageData) {
+	case "image/webp":
+		return webpDimensions(imageData)
+	case "image/avif", "image/heic":
+		return heifDimensions(imageData)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	width, height := config.Width, config.Height
+	if format == "jpeg" && jpegOrientation(imageData) >= 5 {
+		// Orientations 5-8 rotate the image by 90 or 270 degrees.
+		width, height = height, width
+	}
+
+	return width, height, nil
+}
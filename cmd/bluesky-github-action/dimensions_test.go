@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// testBox builds an ISO base media box with the given type and payload.
+func testBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box[0:4], uint32(8+len(body)))
+	copy(box[4:8], boxType)
+	return append(box, body...)
+}
+
+// testHEIF builds a minimal AVIF or HEIC file with the given image spatial
+// extents and optional rotation.
+func testHEIF(brand string, rotation int, extents ...[2]uint32) []byte {
+	var properties [][]byte
+	for _, extent := range extents {
+		ispe := make([]byte, 12)
+		binary.BigEndian.PutUint32(ispe[4:8], extent[0])
+		binary.BigEndian.PutUint32(ispe[8:12], extent[1])
+		properties = append(properties, testBox("ispe", ispe))
+	}
+	if rotation != 0 {
+		properties = append(properties, testBox("irot", []byte{byte(rotation)}))
+	}
+
+	ftyp := testBox("ftyp", []byte(brand), make([]byte, 4), []byte("mif1"))
+	meta := testBox("meta", make([]byte, 4), testBox("hdlr", make([]byte, 24)), testBox("iprp", testBox("ipco", properties...)))
+	return append(ftyp, meta...)
+}
+
+// testWebP builds a WebP header with the given chunk type and chunk payload.
+func testWebP(chunkType string, chunk []byte) []byte {
+	chunk = append(chunk, make([]byte, 16)...)
+	data := []byte("RIFF\x00\x00\x00\x00WEBP" + chunkType)
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(chunk)))
+	data = append(data, size...)
+	return append(data, chunk...)
+}
+
+// testJPEG encodes a JPEG image and inserts an EXIF segment with the given orientation.
+func testJPEG(t *testing.T, width, height, orientation int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("Failed to encode test JPEG: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	// Little-endian TIFF header with a single orientation entry in IFD0.
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00}
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:2], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:4], 3)
+	binary.LittleEndian.PutUint32(entry[4:8], 1)
+	binary.LittleEndian.PutUint16(entry[8:10], uint16(orientation))
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0x00, 0x00}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	result := append([]byte{}, encoded[:2]...)
+	result = append(result, app1...)
+	return append(result, encoded[2:]...)
+}
+
+func TestDecodeImageDimensions(t *testing.T) {
+	vp8 := []byte{0x9D, 0x01, 0x2A, 0x9D, 0x01, 0x2A, 0x80, 0x07, 0x38, 0x04}
+	vp8l := []byte{0x2F, 0x00, 0x00, 0x00, 0x00}
+	binary.LittleEndian.PutUint32(vp8l[1:5], uint32(1279)|uint32(719)<<14)
+	vp8x := []byte{0x10, 0x00, 0x00, 0x00, 0x7F, 0x07, 0x00, 0xAF, 0x04, 0x00}
+
+	tests := []struct {
+		name       string
+		imageData  []byte
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{
+			name:       "lossy WebP",
+			imageData:  testWebP("VP8 ", vp8),
+			wantWidth:  1920,
+			wantHeight: 1080,
+		},
+		{
+			name:       "lossless WebP",
+			imageData:  testWebP("VP8L", vp8l),
+			wantWidth:  1280,
+			wantHeight: 720,
+		},
+		{
+			name:       "extended WebP",
+			imageData:  testWebP("VP8X", vp8x),
+			wantWidth:  1920,
+			wantHeight: 1200,
+		},
+		{
+			name:      "truncated WebP",
+			imageData: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+			wantErr:   true,
+		},
+		{
+			name:       "AVIF",
+			imageData:  testHEIF("avif", 0, [2]uint32{4032, 3024}),
+			wantWidth:  4032,
+			wantHeight: 3024,
+		},
+		{
+			name:       "AVIF with thumbnail uses largest extent",
+			imageData:  testHEIF("avif", 0, [2]uint32{320, 240}, [2]uint32{4032, 3024}),
+			wantWidth:  4032,
+			wantHeight: 3024,
+		},
+		{
+			name:       "HEIC rotated 90 degrees",
+			imageData:  testHEIF("heic", 1, [2]uint32{4032, 3024}),
+			wantWidth:  3024,
+			wantHeight: 4032,
+		},
+		{
+			name:       "AVIF extent overflowing int is ignored",
+			imageData:  testHEIF("avif", 0, [2]uint32{0xFFFFFFFF, 0xFFFFFFFF}, [2]uint32{4032, 3024}),
+			wantWidth:  4032,
+			wantHeight: 3024,
+		},
+		{
+			name:      "AVIF without extent",
+			imageData: testHEIF("avif", 0),
+			wantErr:   true,
+		},
+		{
+			name:       "JPEG without orientation",
+			imageData:  testJPEG(t, 40, 20, 1),
+			wantWidth:  40,
+			wantHeight: 20,
+		},
+		{
+			name:       "JPEG rotated 180 degrees",
+			imageData:  testJPEG(t, 40, 20, 3),
+			wantWidth:  40,
+			wantHeight: 20,
+		},
+		{
+			name:       "JPEG rotated 90 degrees",
+			imageData:  testJPEG(t, 40, 20, 6),
+			wantWidth:  20,
+			wantHeight: 40,
+		},
+		{
+			name:       "JPEG rotated 270 degrees",
+			imageData:  testJPEG(t, 40, 20, 8),
+			wantWidth:  20,
+			wantHeight: 40,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			width, height, err := decodeImageDimensions(tc.imageData)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("decodeImageDimensions() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if width != tc.wantWidth || height != tc.wantHeight {
+				t.Errorf("decodeImageDimensions() = %dx%d, want %dx%d", width, height, tc.wantWidth, tc.wantHeight)
+			}
+		})
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	// testTIFF builds a little-endian TIFF header with the given IFD0 offset,
+	// followed by an IFD with a single orientation entry at offset 8.
+	testTIFF := func(ifdOffset uint32) []byte {
+		tiff := []byte{'I', 'I', 0x2A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00}
+		binary.LittleEndian.PutUint32(tiff[4:8], ifdOffset)
+		entry := make([]byte, 12)
+		binary.LittleEndian.PutUint16(entry[0:2], 0x0112)
+		binary.LittleEndian.PutUint16(entry[8:10], 6)
+		return append(tiff, entry...)
+	}
+
+	tests := []struct {
+		name string
+		tiff []byte
+		want int
+	}{
+		{name: "orientation entry", tiff: testTIFF(8), want: 6},
+		{name: "out-of-range IFD offset", tiff: testTIFF(0xFFFFFFFF), want: 1},
+		{name: "IFD offset past the end", tiff: testTIFF(21), want: 1},
+		{name: "truncated IFD", tiff: testTIFF(8)[:16], want: 1},
+		{name: "invalid byte order", tiff: append([]byte("XX"), testTIFF(8)[2:]...), want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exifOrientation(tc.tiff); got != tc.want {
+				t.Errorf("exifOrientation() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetImageDimensionsWebP(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	imageData := testWebP("VP8X", []byte{0x10, 0x00, 0x00, 0x00, 0x7F, 0x07, 0x00, 0xAF, 0x04, 0x00})
+	aspectRatio := getImageDimensions(imageData, logger)
+	if aspectRatio == nil {
+		t.Fatal("getImageDimensions() returned nil for WebP image")
+	}
+	if aspectRatio.Width != 1920 || aspectRatio.Height != 1200 {
+		t.Errorf("getImageDimensions() = %dx%d, want 1920x1200", aspectRatio.Width, aspectRatio.Height)
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// A 2x1 image with a red left pixel and a blue right pixel.
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		name        string
+		orientation int
+		want        [][]color.RGBA
+	}{
+		{
+			name:        "normal",
+			orientation: 1,
+			want:        [][]color.RGBA{{red, blue}},
+		},
+		{
+			name:        "mirrored horizontally",
+			orientation: 2,
+			want:        [][]color.RGBA{{blue, red}},
+		},
+		{
+			name:        "rotated 90 degrees clockwise",
+			orientation: 6,
+			want:        [][]color.RGBA{{red}, {blue}},
+		},
+		{
+			name:        "rotated 90 degrees counter-clockwise",
+			orientation: 8,
+			want:        [][]color.RGBA{{blue}, {red}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := orientImage(src, tc.orientation)
+			bounds := got.Bounds()
+			if bounds.Dy() != len(tc.want) || bounds.Dx() != len(tc.want[0]) {
+				t.Fatalf("orientImage() size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), len(tc.want[0]), len(tc.want))
+			}
+			for y, row := range tc.want {
+				for x, want := range row {
+					if got := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA); got != want {
+						t.Errorf("orientImage() pixel (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
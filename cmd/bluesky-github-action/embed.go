@@ -31,6 +31,7 @@ type EmbedExternalContent struct {
 	URI         string `json:"uri"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	Thumb       *Blob  `json:"thumb,omitempty"`
 }
 
 // BlobRef represents a reference to a blob.
@@ -67,13 +68,18 @@ type EmbedImages struct {
 
 // EmbedVideo represents a video embed.
 type EmbedVideo struct {
-	Type        string       `json:"$type"`
-	Video       Blob         `json:"video"`
-	AspectRatio *AspectRatio `json:"aspectRatio,omitempty"`
-	Alt         string       `json:"alt,omitempty"`
-	Captions    []Caption    `json:"captions,omitempty"`
+	Type         string       `json:"$type"`
+	Video        Blob         `json:"video"`
+	AspectRatio  *AspectRatio `json:"aspectRatio,omitempty"`
+	Alt          string       `json:"alt,omitempty"`
+	Captions     []Caption    `json:"captions,omitempty"`
+	Presentation string       `json:"presentation,omitempty"`
 }
 
+// videoPresentationGIF presents a video like a GIF: looping, muted, and
+// without playback controls.
+const videoPresentationGIF = "gif"
+
 // Caption represents a video caption/subtitle.
 type Caption struct {
 	Lang string `json:"lang"`
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// maxPostGraphemes is the maximum length of post text in graphemes.
+const maxPostGraphemes = 300
+
+// discardedInput describes an input that is ignored because a post has a
+// single embed, chosen by the priority video > images > link card.
+type discardedInput struct {
+	Input  string // Name of the ignored input.
+	Reason string // Input that takes priority over it.
+	URL    string // URL of an ignored link card.
+}
+
+// discardedEmbedInputs returns the inputs ignored by the embed priority. The
+// page of the summarized release, if any, is the preferred link card.
+func discardedEmbedInputs(args ActionInputs, facets []RichTextFacet, release *GitHubRelease) []discardedInput {
+	var discarded []discardedInput
+
+	hasImages := args.ImagePaths != "" || args.MediaManifest != ""
+	if args.VideoPath != "" && args.ImagePaths != "" {
+		discarded = append(discarded, discardedInput{Input: "image-paths", Reason: "video-path"})
+	}
+	if args.VideoPath != "" && args.MediaManifest != "" {
+		discarded = append(discarded, discardedInput{Input: "media-manifest", Reason: "video-path"})
+	}
+
+	if args.VideoPath == "" && !hasImages {
+		return discarded
+	}
+
+	reason := "video-path"
+	if args.VideoPath == "" {
+		reason = "image-paths"
+		if args.ImagePaths == "" {
+			reason = "media-manifest"
+		}
+	}
+
+	opts := linkCardOptions(args, release)
+	if uri := strings.TrimSpace(opts.URL); uri != "" {
+		return append(discarded, discardedInput{Input: "link-card-url", Reason: reason, URL: uri})
+	}
+	if !args.EnableEmbeds && !opts.hasOverride() {
+		return discarded
+	}
+
+	// Invalid selection options only fail when the link card is built.
+	candidates, err := linkCardCandidates(facets, opts)
+	if err != nil {
+		candidates = nil
+	}
+	if candidates = preferLinkCardURL(candidates, opts.PreferredURL); len(candidates) > 0 {
+		discarded = append(discarded, discardedInput{Input: "link card", Reason: reason, URL: candidates[0]})
+	}
+	return discarded
+}
+
+// appendLinkURL appends a URL on its own line to the post text unless the text
+// already contains it. It reports whether the text fits the length limit.
+func appendLinkURL(text, uri string) (string, bool) {
+	if strings.Contains(text, uri) {
+		return text, true
+	}
+
+	appended := uri
+	if text != "" {
+		appended = text + "\n" + uri
+	}
+	if graphemeCount(appended) > maxPostGraphemes {
+		return text, false
+	}
+	return appended, true
+}
+
+// applyEmbedPriority reports the inputs ignored by the embed priority before
+// anything is uploaded, failing in strict mode. It returns the post text, with
+// the URL of an ignored link card appended when it should be kept as a link.
+func applyEmbedPriority(args ActionInputs, facets []RichTextFacet, release *GitHubRelease, logger *slog.Logger) (string, error) {
+	discarded := discardedEmbedInputs(args, facets, release)
+
+	if args.EmbedStrict && len(discarded) > 0 {
+		var names []string
+		for _, d := range discarded {
+			names = append(names, fmt.Sprintf("%s (overridden by %s)", d.Input, d.Reason))
+		}
+		return "", fmt.Errorf("a post has a single embed, ignored inputs: %s", strings.Join(names, ", "))
+	}
+
+	text := args.Text
+	for _, d := range discarded {
+		logger.Warn("Input ignored, a post has a single embed (priority: video > images > link card)",
+			"input", d.Input,
+			"overriddenBy", d.Reason,
+			"url", d.URL,
+		)
+
+		if d.URL == "" || !args.LinkCardKeepURL {
+			continue
+		}
+
+		appended, ok := appendLinkURL(text, d.URL)
+		if !ok {
+			logger.Warn("Link card URL not added to the post text, it would exceed the length limit", "url", d.URL, "limit", maxPostGraphemes)
+			continue
+		}
+		if appended != text {
+			logger.Info("Link card URL added to the post text", "url", d.URL)
+		}
+		text = appended
+	}
+
+	return text, nil
+}
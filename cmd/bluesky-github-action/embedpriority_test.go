@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscardedEmbedInputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    ActionInputs
+		release *GitHubRelease
+		want    []discardedInput
+	}{
+		{
+			name: "text only",
+			args: ActionInputs{Text: "Release https://example.com/v1", EnableEmbeds: true},
+		},
+		{
+			name: "link card without media",
+			args: ActionInputs{Text: "Release", LinkCardURL: "https://example.com/v1"},
+		},
+		{
+			name: "video overrides images and manifest",
+			args: ActionInputs{VideoPath: "demo.mp4", ImagePaths: "a.png", MediaManifest: "media.yml"},
+			want: []discardedInput{
+				{Input: "image-paths", Reason: "video-path"},
+				{Input: "media-manifest", Reason: "video-path"},
+			},
+		},
+		{
+			name: "images override explicit link card",
+			args: ActionInputs{ImagePaths: "a.png", LinkCardURL: " https://example.com/v1 "},
+			want: []discardedInput{{Input: "link-card-url", Reason: "image-paths", URL: "https://example.com/v1"}},
+		},
+		{
+			name: "manifest overrides detected link card",
+			args: ActionInputs{Text: "See https://example.com/v1 and https://example.com/v2", MediaManifest: "media.yml", EnableEmbeds: true},
+			want: []discardedInput{{Input: "link card", Reason: "media-manifest", URL: "https://example.com/v1"}},
+		},
+		{
+			name: "detected link card follows the selection policy",
+			args: ActionInputs{Text: "See https://example.com/v1 and https://example.com/v2", VideoPath: "demo.mp4", EnableEmbeds: true, LinkCardSelection: "last"},
+			want: []discardedInput{{Input: "link card", Reason: "video-path", URL: "https://example.com/v2"}},
+		},
+		{
+			name:    "images override release link card",
+			args:    ActionInputs{Text: "See https://example.com/docs", ImagePaths: "a.png", EnableEmbeds: true},
+			release: &GitHubRelease{TagName: "v1", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1"},
+			want:    []discardedInput{{Input: "link card", Reason: "image-paths", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1"}},
+		},
+		{
+			name:    "release link card with embeds disabled",
+			args:    ActionInputs{ImagePaths: "a.png"},
+			release: &GitHubRelease{TagName: "v1", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1"},
+		},
+		{
+			name: "detected link card with embeds disabled",
+			args: ActionInputs{Text: "See https://example.com/v1", ImagePaths: "a.png"},
+		},
+		{
+			name: "media without URLs",
+			args: ActionInputs{Text: "New screenshot", ImagePaths: "a.png", EnableEmbeds: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := discardedEmbedInputs(tc.args, parseRichTextFacets(tc.args.Text), tc.release)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("discardedEmbedInputs() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAppendLinkURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		uri    string
+		want   string
+		wantOK bool
+	}{
+		{name: "appended on its own line", text: "New release", uri: "https://example.com/v1", want: "New release\nhttps://example.com/v1", wantOK: true},
+		{name: "empty text", text: "", uri: "https://example.com/v1", want: "https://example.com/v1", wantOK: true},
+		{name: "already in text", text: "See https://example.com/v1", uri: "https://example.com/v1", want: "See https://example.com/v1", wantOK: true},
+		{name: "exceeds length limit", text: strings.Repeat("a", 290), uri: "https://example.com/v1", want: strings.Repeat("a", 290)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := appendLinkURL(tc.text, tc.uri)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("appendLinkURL() = %q, %v, want %q, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestApplyEmbedPriority(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name    string
+		args    ActionInputs
+		release *GitHubRelease
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "nothing discarded",
+			args: ActionInputs{Text: "New release", LinkCardURL: "https://example.com/v1", EmbedStrict: true},
+			want: "New release",
+		},
+		{
+			name: "discarded link card is dropped by default",
+			args: ActionInputs{Text: "New release", ImagePaths: "a.png", LinkCardURL: "https://example.com/v1"},
+			want: "New release",
+		},
+		{
+			name: "discarded link card URL is kept",
+			args: ActionInputs{Text: "New release", ImagePaths: "a.png", LinkCardURL: "https://example.com/v1", LinkCardKeepURL: true},
+			want: "New release\nhttps://example.com/v1",
+		},
+		{
+			name: "detected link card URL stays in text",
+			args: ActionInputs{Text: "See https://example.com/v1", VideoPath: "demo.mp4", EnableEmbeds: true, LinkCardKeepURL: true},
+			want: "See https://example.com/v1",
+		},
+		{
+			name: "URL exceeding the length limit is not kept",
+			args: ActionInputs{Text: strings.Repeat("a", 295), ImagePaths: "a.png", LinkCardURL: "https://example.com/v1", LinkCardKeepURL: true},
+			want: strings.Repeat("a", 295),
+		},
+		{
+			name:    "discarded release link card URL is kept",
+			args:    ActionInputs{Text: "New release", ImagePaths: "a.png", EnableEmbeds: true, LinkCardKeepURL: true},
+			release: &GitHubRelease{TagName: "v1", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1"},
+			want:    "New release\nhttps://github.com/cbrgm/bluesky-github-action/releases/tag/v1",
+		},
+		{
+			name:    "strict mode fails on discarded release link card",
+			args:    ActionInputs{Text: "New release", ImagePaths: "a.png", EnableEmbeds: true, EmbedStrict: true},
+			release: &GitHubRelease{TagName: "v1", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1"},
+			wantErr: true,
+		},
+		{
+			name:    "strict mode fails",
+			args:    ActionInputs{Text: "New release", VideoPath: "demo.mp4", ImagePaths: "a.png", EmbedStrict: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := applyEmbedPriority(tc.args, parseRichTextFacets(tc.args.Text), tc.release, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applyEmbedPriority() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("applyEmbedPriority() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
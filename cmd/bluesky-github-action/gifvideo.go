@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Constants for converting animated GIFs to videos.
+const (
+	gifTimescale        = 100 // GIF frame delays are given in hundredths of a second.
+	gifMinFrameDelay    = 2   // Shorter delays are shown at gifDefaultDelay, as browsers do.
+	gifDefaultDelay     = 10
+	gifJPEGQuality      = 90
+	gifTranscodeTimeout = 2 * time.Minute
+)
+
+// ffmpegCommand is the name of the ffmpeg executable looked up in PATH.
+var ffmpegCommand = "ffmpeg"
+
+// decodeAnimatedGIF decodes GIF data with more than one frame. It returns nil
+// for other images and for GIFs with a single frame.
+func decodeAnimatedGIF(data []byte) *gif.GIF {
+	if sniffImageMimeType(data) != "image/gif" {
+		return nil
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil || len(g.Image) < 2 {
+		return nil
+	}
+	return g
+}
+
+// gifFrameDelay returns the delay of a GIF frame in hundredths of a second.
+func gifFrameDelay(g *gif.GIF, index int) int {
+	if index >= len(g.Delay) || g.Delay[index] < gifMinFrameDelay {
+		return gifDefaultDelay
+	}
+	return g.Delay[index]
+}
+
+// renderGIFFrames draws the frames of an animated GIF onto a canvas of its
+// logical screen size, applying the disposal method of each frame, and calls
+// fn with the canvas after each frame. Transparent areas are rendered white.
+func renderGIFFrames(g *gif.GIF, fn func(index int, frame image.Image) error) error {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	for _, frame := range g.Image {
+		bounds = bounds.Union(frame.Bounds())
+	}
+
+	background := image.NewUniform(color.White)
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, background, image.Point{}, draw.Src)
+
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if err := fn(i, canvas); err != nil {
+			return err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), background, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return nil
+}
+
+// movBox encodes a QuickTime atom with the given type and payload.
+func movBox(boxType string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+
+	box := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(box[0:4], uint32(size))
+	copy(box[4:8], boxType)
+	for _, p := range payload {
+		box = append(box, p...)
+	}
+	return box
+}
+
+// movUint32s encodes big-endian 32-bit fields.
+func movUint32s(values ...uint32) []byte {
+	buf := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		buf = binary.BigEndian.AppendUint32(buf, v)
+	}
+	return buf
+}
+
+// movCompressorName encodes the 32-byte Pascal string naming the compressor
+// of a video sample description.
+func movCompressorName(name string) []byte {
+	buf := make([]byte, 32)
+	buf[0] = byte(copy(buf[1:], name))
+	return buf
+}
+
+// movIdentityMatrix is the identity transformation matrix of movie and track headers.
+var movIdentityMatrix = movUint32s(0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000)
+
+// encodeGIFAsMOV encodes an animated GIF as a QuickTime movie with one
+// Motion-JPEG frame per GIF frame. This needs no external tools; the video
+// service transcodes the movie for playback.
+func encodeGIFAsMOV(g *gif.GIF) ([]byte, error) {
+	var frames bytes.Buffer
+	var sizes []uint32
+	var timeToSample []uint32 // Pairs of sample count and delay.
+	var duration uint32
+	var width, height int
+
+	err := renderGIFFrames(g, func(index int, frame image.Image) error {
+		start := frames.Len()
+		if err := jpeg.Encode(&frames, frame, &jpeg.Options{Quality: gifJPEGQuality}); err != nil {
+			return fmt.Errorf("failed to encode frame %d: %w", index+1, err)
+		}
+		sizes = append(sizes, uint32(frames.Len()-start))
+		width, height = frame.Bounds().Dx(), frame.Bounds().Dy()
+
+		delay := uint32(gifFrameDelay(g, index))
+		duration += delay
+		if n := len(timeToSample); n > 0 && timeToSample[n-1] == delay {
+			timeToSample[n-2]++
+		} else {
+			timeToSample = append(timeToSample, 1, delay)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ftyp := movBox("ftyp", []byte(quickTimeBrand), movUint32s(0x200), []byte(quickTimeBrand))
+	mdatHeaderSize := 8
+	chunkOffset := uint32(len(ftyp) + mdatHeaderSize)
+
+	mvhd := movBox("mvhd",
+		movUint32s(0, 0, 0, gifTimescale, duration, 0x00010000),
+		[]byte{0x01, 0x00}, // Volume.
+		make([]byte, 10),   // Reserved.
+		movIdentityMatrix,
+		make([]byte, 24), // Preview, poster, selection, and current time.
+		movUint32s(2),    // Next track ID.
+	)
+
+	tkhd := movBox("tkhd",
+		movUint32s(0x00000003, 0, 0, 1, 0, duration, 0, 0),
+		make([]byte, 8), // Layer, alternate group, volume, and reserved.
+		movIdentityMatrix,
+		movUint32s(uint32(width)<<16, uint32(height)<<16),
+	)
+
+	sampleEntry := bytes.Join([][]byte{
+		make([]byte, 6),      // Reserved.
+		{0x00, 0x01},         // Data reference index.
+		make([]byte, 4),      // Version and revision.
+		make([]byte, 4),      // Vendor.
+		movUint32s(0, 0x200), // Temporal and spatial quality.
+		{byte(width >> 8), byte(width), byte(height >> 8), byte(height)}, // Width and height.
+		movUint32s(72<<16, 72<<16, 0),                                    // Resolution and data size.
+		{0x00, 0x01},                                                     // Frame count.
+		movCompressorName("Photo - JPEG"),
+		{0x00, 0x18, 0xFF, 0xFF}, // Depth and default color table.
+	}, nil)
+
+	stbl := movBox("stbl",
+		movBox("stsd", movUint32s(0, 1), movBox("jpeg", sampleEntry)),
+		movBox("stts", movUint32s(0, uint32(len(timeToSample)/2)), movUint32s(timeToSample...)),
+		movBox("stsc", movUint32s(0, 1, 1, uint32(len(sizes)), 1)),
+		movBox("stsz", movUint32s(0, 0, uint32(len(sizes))), movUint32s(sizes...)),
+		movBox("stco", movUint32s(0, 1, chunkOffset)),
+	)
+
+	mdia := movBox("mdia",
+		movBox("mdhd", movUint32s(0, 0, 0, gifTimescale, duration), make([]byte, 4)),
+		movBox("hdlr", []byte("\x00\x00\x00\x00mhlrvide"), make([]byte, 12), []byte{0}),
+		movBox("minf",
+			movBox("vmhd", movUint32s(1), make([]byte, 8)),
+			movBox("dinf", movBox("dref", movUint32s(0, 1), movBox("alis", movUint32s(1)))),
+			stbl,
+		),
+	)
+
+	moov := movBox("moov", mvhd, movBox("trak", tkhd, mdia))
+	return bytes.Join([][]byte{ftyp, movBox("mdat", frames.Bytes()), moov}, nil), nil
+}
+
+// transcodeGIFWithFFmpeg converts a GIF file to an H.264 MP4 video with ffmpeg.
+// Dimensions are rounded down to even numbers as required by the encoder.
+func transcodeGIFWithFFmpeg(ffmpeg, gifPath, videoPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gifTranscodeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", gifPath,
+		"-movflags", "+faststart",
+		"-pix_fmt", "yuv420p",
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-c:v", "libx264",
+		videoPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// convertGIFToVideo writes an animated GIF as a video file to dir and returns
+// its path. It uses ffmpeg when available and falls back to a Motion-JPEG
+// QuickTime movie encoded in Go.
+func convertGIFToVideo(dir string, data []byte, g *gif.GIF, logger *slog.Logger) (string, error) {
+	if ffmpeg, err := exec.LookPath(ffmpegCommand); err == nil {
+		gifPath := filepath.Join(dir, "animation.gif")
+		videoPath := filepath.Join(dir, "animation.mp4")
+
+		err := os.WriteFile(gifPath, data, 0600)
+		if err == nil {
+			err = transcodeGIFWithFFmpeg(ffmpeg, gifPath, videoPath)
+		}
+		if err == nil {
+			logger.Debug("Converted GIF to video with ffmpeg", "ffmpeg", ffmpeg)
+			return videoPath, nil
+		}
+		logger.Warn("Converting GIF with ffmpeg failed, encoding Motion-JPEG video instead", "err", err)
+	}
+
+	movie, err := encodeGIFAsMOV(g)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert GIF to video: %w", err)
+	}
+
+	videoPath := filepath.Join(dir, "animation.mov")
+	if err := os.WriteFile(videoPath, movie, 0600); err != nil {
+		return "", fmt.Errorf("failed to write converted GIF: %w", err)
+	}
+	logger.Debug("Converted GIF to Motion-JPEG video", "frames", len(g.Image), "size", len(movie))
+	return videoPath, nil
+}
+
+// processAnimatedGIF uploads an animated GIF as a looping video embed. It
+// returns nil if the image inputs are not a single animated GIF, or if the
+// video service is unavailable and the video fallback is drop, in which case
+// they are posted as images.
+func processAnimatedGIF(args ActionInputs, session *SessionResponse, logger *slog.Logger) (*EmbedVideo, error) {
+	if err := validateVideoFallback(args.VideoFallback); err != nil {
+		return nil, err
+	}
+
+	opts := imageOptions(args)
+	items, err := collectImageItems(args.ImagePaths, args.ImageAltTexts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) != 1 {
+		for _, item := range items {
+			if detectImageMimeType(mediaSourceName(item.Path)) == "image/gif" {
+				logger.Warn("Posting GIF as a static image, only a single GIF can be converted to a video", "path", item.Path)
+			}
+		}
+		return nil, nil
+	}
+	item := items[0]
+
+	data, err := readImageSource(context.Background(), item.Path, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	g := decodeAnimatedGIF(data)
+	if g == nil {
+		return nil, nil
+	}
+
+	alt, err := applyAltTextPolicy(item.Alt, resolveAltText(0, nil), item.Path, maxVideoAltTextGraphemes, opts.AltText, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Converting animated GIF to video", "path", item.Path, "frames", len(g.Image))
+
+	dir, err := os.MkdirTemp("", "bluesky-gif-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	videoPath, err := convertGIFToVideo(dir, data, g, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Captions of the video inputs do not belong to the GIF.
+	videoOpts := videoOptions(args)
+	videoOpts.Captions = ""
+
+	videoEmbed, err := processVideo(args.PDSURL, session.AccessToken, session.UserID, videoPath, alt, videoOpts, logger)
+	if err != nil && args.VideoFallback == videoFallbackDrop && isVideoServiceError(err) {
+		logger.Warn("Video service unavailable, posting GIF as a static image", "path", item.Path, "err", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	videoEmbed.Presentation = videoPresentationGIF
+	switch {
+	case item.AspectRatio != nil:
+		videoEmbed.AspectRatio = item.AspectRatio
+	case g.Config.Width > 0 && g.Config.Height > 0:
+		// The logical screen size of the GIF header, before any rounding by the encoder.
+		videoEmbed.AspectRatio = &AspectRatio{Width: g.Config.Width, Height: g.Config.Height}
+	}
+	return videoEmbed, nil
+}
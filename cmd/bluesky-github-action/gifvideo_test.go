@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testGIFPalette holds the colors of the frames of test GIFs.
+var testGIFPalette = color.Palette{color.White, color.RGBA{R: 255, A: 255}, color.RGBA{G: 255, A: 255}, color.RGBA{B: 255, A: 255}}
+
+// testAnimatedGIF encodes a GIF with one frame per delay, each frame filled
+// with the next color of testGIFPalette.
+func testAnimatedGIF(t *testing.T, width, height int, delays []int) []byte {
+	t.Helper()
+
+	g := &gif.GIF{Config: image.Config{Width: width, Height: height, ColorModel: testGIFPalette}}
+	for i, delay := range delays {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), testGIFPalette)
+		for p := range frame.Pix {
+			frame.Pix[p] = uint8(1 + i%3)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, delay)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Failed to encode test GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// useFFmpeg sets the ffmpeg executable looked up for GIF conversion for the
+// duration of a test.
+func useFFmpeg(t *testing.T, command string) {
+	t.Helper()
+	original := ffmpegCommand
+	ffmpegCommand = command
+	t.Cleanup(func() { ffmpegCommand = original })
+}
+
+func TestDecodeAnimatedGIF(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{name: "animated GIF", data: testAnimatedGIF(t, 8, 8, []int{10, 10}), want: true},
+		{name: "single frame GIF", data: testAnimatedGIF(t, 8, 8, []int{10})},
+		{name: "PNG", data: fakeImageData(pngMagic, 64)},
+		{name: "truncated GIF", data: []byte("GIF89a\x08\x00")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := decodeAnimatedGIF(tc.data) != nil; got != tc.want {
+				t.Errorf("decodeAnimatedGIF() animated = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderGIFFrames(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), testGIFPalette)
+	corner := image.NewPaletted(image.Rect(2, 2, 4, 4), testGIFPalette)
+	for p := range full.Pix {
+		full.Pix[p] = 1
+	}
+	for p := range corner.Pix {
+		corner.Pix[p] = 3
+	}
+
+	tests := []struct {
+		name     string
+		disposal byte
+		want     color.Color // Top-left pixel of the third frame.
+	}{
+		{name: "keep previous frame", disposal: gif.DisposalNone, want: red},
+		{name: "restore to background", disposal: gif.DisposalBackground, want: color.White},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &gif.GIF{
+				Config:   image.Config{Width: 4, Height: 4},
+				Image:    []*image.Paletted{full, corner, corner},
+				Disposal: []byte{tc.disposal, gif.DisposalPrevious, gif.DisposalNone},
+			}
+
+			var pixels []color.Color
+			err := renderGIFFrames(g, func(index int, frame image.Image) error {
+				pixels = append(pixels, frame.At(0, 0), frame.At(3, 3))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("renderGIFFrames() unexpected error = %v", err)
+			}
+
+			blue := color.RGBA{B: 255, A: 255}
+			if !sameColor(pixels[3], blue) {
+				t.Errorf("second frame corner = %v, want %v", pixels[3], blue)
+			}
+			if !sameColor(pixels[4], tc.want) {
+				t.Errorf("third frame top-left = %v, want %v", pixels[4], tc.want)
+			}
+		})
+	}
+}
+
+// sameColor reports whether two colors have the same RGBA values.
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestEncodeGIFAsMOV(t *testing.T) {
+	g := decodeAnimatedGIF(testAnimatedGIF(t, 48, 32, []int{5, 0, 20}))
+	if g == nil {
+		t.Fatal("decodeAnimatedGIF() returned nil for test GIF")
+	}
+
+	movie, err := encodeGIFAsMOV(g)
+	if err != nil {
+		t.Fatalf("encodeGIFAsMOV() unexpected error = %v", err)
+	}
+
+	meta, err := probeVideo(bytes.NewReader(movie), int64(len(movie)))
+	if err != nil {
+		t.Fatalf("probeVideo() unexpected error = %v", err)
+	}
+	// A delay of zero is shown as 10 hundredths of a second.
+	if meta.MimeType != "video/quicktime" || meta.Duration != 350*time.Millisecond || meta.Width != 48 || meta.Height != 32 {
+		t.Errorf("probeVideo() = %+v, want 48x32 QuickTime movie of 350ms", meta)
+	}
+
+	// Each sample must be a JPEG of the composed frame at the offset given by the sample table.
+	stbl := findBox(findBox(findBox(findBox(findBox(movie, "moov"), "trak"), "mdia"), "minf"), "stbl")
+	stsz := findBox(stbl, "stsz")
+	stco := findBox(stbl, "stco")
+	if len(stsz) < 12 || len(stco) < 12 {
+		t.Fatalf("sample table incomplete: stsz %d bytes, stco %d bytes", len(stsz), len(stco))
+	}
+
+	count := int(binary.BigEndian.Uint32(stsz[8:12]))
+	if count != 3 {
+		t.Fatalf("sample count = %d, want 3", count)
+	}
+
+	offset := int(binary.BigEndian.Uint32(stco[8:12]))
+	wantColors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	for i := 0; i < count; i++ {
+		size := int(binary.BigEndian.Uint32(stsz[12+4*i:]))
+		frame, err := jpeg.Decode(bytes.NewReader(movie[offset : offset+size]))
+		if err != nil {
+			t.Fatalf("sample %d is not a JPEG: %v", i+1, err)
+		}
+
+		r, g, b, _ := frame.At(24, 16).RGBA()
+		want := wantColors[i]
+		if absDiff(r>>8, uint32(want.R)) > 8 || absDiff(g>>8, uint32(want.G)) > 8 || absDiff(b>>8, uint32(want.B)) > 8 {
+			t.Errorf("sample %d color = %d,%d,%d, want %v", i+1, r>>8, g>>8, b>>8, want)
+		}
+		offset += size
+	}
+}
+
+// absDiff returns the absolute difference of two color components.
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func TestProcessImageMediaGIF(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	useFFmpeg(t, "ffmpeg-not-installed")
+
+	tempDir := t.TempDir()
+	gifPath := filepath.Join(tempDir, "demo.gif")
+	if err := os.WriteFile(gifPath, testAnimatedGIF(t, 300, 200, []int{10, 10, 10}), 0644); err != nil {
+		t.Fatalf("Failed to write test GIF: %v", err)
+	}
+	pngPath := filepath.Join(tempDir, "screenshot.png")
+	if err := os.WriteFile(pngPath, fakeImageData(pngMagic, 64), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+
+	var uploadedType string
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getServiceAuth"):
+			json.NewEncoder(w).Encode(ServiceAuthResponse{Token: "service-token"})
+		case strings.Contains(r.URL.Path, "uploadBlob"):
+			body, _ := io.ReadAll(r.Body)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"blob": Blob{Type: "blob", Ref: BlobRef{Link: "bafkreiimage"}, MimeType: r.Header.Get("Content-Type"), Size: len(body)},
+			})
+		}
+	}))
+	defer pdsServer.Close()
+
+	videoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getUploadLimits"):
+			w.Write([]byte(`{"canUpload": true}`))
+		case strings.Contains(r.URL.Path, "uploadVideo"):
+			uploadedType = r.Header.Get("Content-Type")
+			io.Copy(io.Discard, r.Body)
+			json.NewEncoder(w).Encode(VideoUploadResponse{
+				JobID: "job123",
+				Status: &VideoJobStatus{
+					Blob: &Blob{Type: "blob", Ref: BlobRef{Link: "bafkreigif"}, MimeType: "video/mp4", Size: 1024},
+				},
+			})
+		}
+	}))
+	defer videoServer.Close()
+	useVideoService(t, videoServer.URL)
+
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:test"}
+
+	t.Run("single animated GIF becomes a looping video", func(t *testing.T) {
+		args := ActionInputs{PDSURL: pdsServer.URL, ImagePaths: gifPath, ImageAltTexts: "Demo animation", ImageGIFToVideo: true}
+
+		embed, replies, err := processImageMedia(args, session, logger)
+		if err != nil {
+			t.Fatalf("processImageMedia() unexpected error = %v", err)
+		}
+
+		videoEmbed, ok := embed.(*EmbedVideo)
+		if !ok || len(replies) != 0 {
+			t.Fatalf("processImageMedia() = %T with %d replies, want *EmbedVideo", embed, len(replies))
+		}
+		if videoEmbed.Presentation != "gif" || videoEmbed.Alt != "Demo animation" || videoEmbed.Video.Ref.Link != "bafkreigif" {
+			t.Errorf("processImageMedia() = %+v, want GIF presentation with alt text", videoEmbed)
+		}
+		if ar := videoEmbed.AspectRatio; ar == nil || ar.Width != 300 || ar.Height != 200 {
+			t.Errorf("processImageMedia() aspectRatio = %v, want 300x200", ar)
+		}
+		if uploadedType != "video/quicktime" {
+			t.Errorf("uploaded video type = %s, want video/quicktime", uploadedType)
+		}
+	})
+
+	tests := []struct {
+		name string
+		args ActionInputs
+	}{
+		{
+			name: "conversion disabled",
+			args: ActionInputs{PDSURL: pdsServer.URL, ImagePaths: gifPath},
+		},
+		{
+			name: "GIF among other images",
+			args: ActionInputs{PDSURL: pdsServer.URL, ImagePaths: gifPath + "," + pngPath, ImageGIFToVideo: true},
+		},
+		{
+			name: "no GIF",
+			args: ActionInputs{PDSURL: pdsServer.URL, ImagePaths: pngPath, ImageGIFToVideo: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			embed, _, err := processImageMedia(tc.args, session, logger)
+			if err != nil {
+				t.Fatalf("processImageMedia() unexpected error = %v", err)
+			}
+			if _, ok := embed.(*EmbedImages); !ok {
+				t.Errorf("processImageMedia() = %T, want *EmbedImages", embed)
+			}
+		})
+	}
+}
+
+func TestProcessImageMediaGIFDrop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	useFFmpeg(t, "ffmpeg-not-installed")
+
+	gifPath := filepath.Join(t.TempDir(), "demo.gif")
+	if err := os.WriteFile(gifPath, testAnimatedGIF(t, 300, 200, []int{10, 10, 10}), 0644); err != nil {
+		t.Fatalf("Failed to write test GIF: %v", err)
+	}
+
+	// The PDS refuses service auth, so the video service cannot be used.
+	var uploadedType string
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "getServiceAuth"):
+			w.WriteHeader(http.StatusBadGateway)
+		case strings.Contains(r.URL.Path, "uploadBlob"):
+			uploadedType = r.Header.Get("Content-Type")
+			body, _ := io.ReadAll(r.Body)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"blob": Blob{Type: "blob", Ref: BlobRef{Link: "bafkreiimage"}, MimeType: uploadedType, Size: len(body)},
+			})
+		}
+	}))
+	defer pdsServer.Close()
+
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:test"}
+
+	tests := []struct {
+		name       string
+		fallback   string
+		wantImages bool
+		wantErr    bool
+	}{
+		{name: "drop posts the GIF as a static image", fallback: videoFallbackDrop, wantImages: true},
+		{name: "fail without fallback", fallback: videoFallbackNone, wantErr: true},
+		{name: "unknown fallback", fallback: "skip", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			uploadedType = ""
+			args := ActionInputs{PDSURL: pdsServer.URL, ImagePaths: gifPath, ImageAltTexts: "Demo animation", ImageGIFToVideo: true, VideoFallback: tc.fallback}
+
+			embed, _, err := processImageMedia(args, session, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("processImageMedia() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantImages {
+				return
+			}
+
+			if _, ok := embed.(*EmbedImages); !ok {
+				t.Errorf("processImageMedia() = %T, want *EmbedImages", embed)
+			}
+			if uploadedType != "image/gif" {
+				t.Errorf("uploaded image type = %s, want image/gif", uploadedType)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Handling of a post that already exists for the idempotency key.
+const (
+	idempotencyModeSkip   = "skip"   // Keep the existing post.
+	idempotencyModeUpdate = "update" // Replace the existing post.
+)
+
+// Special idempotency keys.
+const (
+	idempotencyKeyAuto = "auto" // Derived from the repository, the released or pushed tag, and the step.
+	idempotencyKeyNone = "none" // Disables idempotency.
+)
+
+// tidAlphabet is the base32-sortable alphabet of timestamp identifiers (TIDs).
+const tidAlphabet = "234567abcdefghijklmnopqrstuvwxyz"
+
+// Record keys derived from idempotency keys are TIDs with a timestamp within a
+// year after tidEpoch, so they cannot collide with record keys generated by
+// the PDS for new posts.
+var (
+	tidEpoch = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tidRange = 365 * 24 * time.Hour
+)
+
+// encodeTID encodes a 64-bit value as a 13 character TID.
+func encodeTID(v uint64) string {
+	var tid [13]byte
+	for i := len(tid) - 1; i >= 0; i-- {
+		tid[i] = tidAlphabet[v&31]
+		v >>= 5
+	}
+	return string(tid[:])
+}
+
+// idempotencyRecordKey derives the record key of a post from an idempotency
+// key. The key is hashed into the timestamp and clock identifier of a TID, as
+// the post lexicon requires TID record keys.
+func idempotencyRecordKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := binary.BigEndian.Uint64(sum[:8])
+
+	micros := uint64(tidEpoch.UnixMicro()) + hash%uint64(tidRange.Microseconds())
+	clockID := binary.BigEndian.Uint64(sum[8:16]) & 0x3FF
+	return encodeTID(micros<<10 | clockID)
+}
+
+// resolveIdempotencyKey returns the idempotency key, deriving the auto key
+// from the repository, the tag of the release event or tag push, and the job
+// and step, which stay the same across re-runs. It returns an empty key when
+// idempotency is disabled or the run has no tag.
+func resolveIdempotencyKey(key string, logger *slog.Logger) (string, error) {
+	switch key {
+	case "", idempotencyKeyNone:
+		return "", nil
+	case idempotencyKeyAuto:
+	default:
+		return key, nil
+	}
+
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	_, event, err := loadGitHubEvent(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return "", err
+	}
+
+	tag := ""
+	if event.Release != nil {
+		tag = event.Release.TagName
+	} else if strings.HasPrefix(os.Getenv("GITHUB_REF"), "refs/tags/") {
+		tag = os.Getenv("GITHUB_REF_NAME")
+	}
+	if repository == "" || tag == "" {
+		logger.Info("No release or tag for the idempotency key, posting without idempotency")
+		return "", nil
+	}
+
+	key = repository + "@" + tag
+	for _, part := range []string{os.Getenv("GITHUB_JOB"), os.Getenv("GITHUB_ACTION")} {
+		if part != "" {
+			key += "/" + part
+		}
+	}
+	return key, nil
+}
+
+// getPostRecord looks up a post of the authenticated account by record key.
+// It returns nil if the post does not exist.
+// nolint: errcheck
+func getPostRecord(pdsURL string, session *SessionResponse, rkey string, logger *slog.Logger) (*StrongRef, error) {
+	query := url.Values{
+		"repo":       {session.UserID},
+		"collection": {postCollection},
+		"rkey":       {rkey},
+	}
+	recordURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.getRecord?%s", pdsURL, query.Encode())
+
+	request, err := http.NewRequest("GET", recordURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+session.AccessToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up post %s: %w", rkey, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+
+		var xrpcErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &xrpcErr)
+		if resp.StatusCode == http.StatusNotFound || xrpcErr.Error == "RecordNotFound" {
+			logger.Debug("No existing post for idempotency key", "rkey", rkey)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to look up post %s, status code: %d, body: %s", rkey, resp.StatusCode, string(body))
+	}
+
+	var record StrongRef
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode post %s: %w", rkey, err)
+	}
+	return &record, nil
+}
+
+// findExistingPost derives the record key of the post from the idempotency key
+// and looks up a post already published with it. It returns an empty record
+// key when idempotency is disabled or no key can be derived.
+func findExistingPost(args ActionInputs, session *SessionResponse, logger *slog.Logger) (string, *StrongRef, error) {
+	if args.IdempotencyMode != idempotencyModeSkip && args.IdempotencyMode != idempotencyModeUpdate {
+		return "", nil, fmt.Errorf("invalid idempotency mode %q, expected %s or %s", args.IdempotencyMode, idempotencyModeSkip, idempotencyModeUpdate)
+	}
+
+	key, err := resolveIdempotencyKey(args.IdempotencyKey, logger)
+	if err != nil || key == "" {
+		return "", nil, err
+	}
+	rkey := idempotencyRecordKey(key)
+	logger.Debug("Derived record key from idempotency key", "key", key, "rkey", rkey)
+
+	existing, err := getPostRecord(args.PDSURL, session, rkey, logger)
+	if err != nil {
+		return "", nil, err
+	}
+	return rkey, existing, nil
+}
+
+// publishKeyedPost publishes a post under the given record key, replacing the
+// existing post when there is one. Without a record key, the PDS generates one.
+func publishKeyedPost(pdsURL string, session *SessionResponse, post *Post, rkey string, existing *StrongRef, logger *slog.Logger) (*StrongRef, error) {
+	if rkey == "" {
+		return publishPost(pdsURL, session, post, logger)
+	}
+
+	data := map[string]interface{}{
+		"repo":       session.UserID,
+		"collection": postCollection,
+		"rkey":       rkey,
+		"record":     post,
+	}
+	if existing == nil {
+		return writePostRecord(pdsURL, "com.atproto.repo.createRecord", session, data, logger)
+	}
+
+	// Fail instead of overwriting a post that changed since it was looked up.
+	data["swapRecord"] = existing.CID
+	return writePostRecord(pdsURL, "com.atproto.repo.putRecord", session, data, logger)
+}
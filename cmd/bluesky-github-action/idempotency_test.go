@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tidPattern matches the syntax of TID record keys.
+var tidPattern = regexp.MustCompile(`^[234567abcdefghij][234567abcdefghijklmnopqrstuvwxyz]{12}$`)
+
+// decodeTID decodes a TID into its 64-bit value.
+func decodeTID(tid string) uint64 {
+	var v uint64
+	for _, c := range tid {
+		v = v<<5 | uint64(strings.IndexRune(tidAlphabet, c))
+	}
+	return v
+}
+
+func TestEncodeTID(t *testing.T) {
+	tests := []struct {
+		value uint64
+		want  string
+	}{
+		{value: 0, want: "2222222222222"},
+		{value: 31, want: "222222222222z"},
+		{value: 1<<63 - 1, want: "bzzzzzzzzzzzz"},
+	}
+
+	for _, tc := range tests {
+		if got := encodeTID(tc.value); got != tc.want {
+			t.Errorf("encodeTID(%d) = %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestIdempotencyRecordKey(t *testing.T) {
+	rkey := idempotencyRecordKey("cbrgm/bluesky-github-action@v1.2.0")
+
+	if !tidPattern.MatchString(rkey) {
+		t.Fatalf("idempotencyRecordKey() = %q, not a TID", rkey)
+	}
+	if again := idempotencyRecordKey("cbrgm/bluesky-github-action@v1.2.0"); again != rkey {
+		t.Errorf("idempotencyRecordKey() not deterministic: %q != %q", again, rkey)
+	}
+	if other := idempotencyRecordKey("cbrgm/bluesky-github-action@v1.2.1"); other == rkey {
+		t.Errorf("idempotencyRecordKey() = %q for different keys", other)
+	}
+
+	timestamp := time.UnixMicro(int64(decodeTID(rkey) >> 10))
+	if timestamp.Before(tidEpoch) || !timestamp.Before(tidEpoch.Add(tidRange)) {
+		t.Errorf("idempotencyRecordKey() timestamp = %v, want within a year after %v", timestamp, tidEpoch)
+	}
+}
+
+func TestResolveIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		payload string
+		ref     string
+		step    string
+		noStep  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "explicit key", key: "weekly-digest-2024-05", payload: `{}`, want: "weekly-digest-2024-05"},
+		{name: "auto key from release event", key: "auto", payload: testReleasePayload, want: "cbrgm/bluesky-github-action@v1.2.0/announce/bluesky_post"},
+		{name: "auto key from tag push", key: "auto", payload: `{}`, ref: "refs/tags/v2.0.0", want: "cbrgm/bluesky-github-action@v2.0.0/announce/bluesky_post"},
+		{name: "auto key of another step", key: "auto", payload: testReleasePayload, step: "bluesky_post_de", want: "cbrgm/bluesky-github-action@v1.2.0/announce/bluesky_post_de"},
+		{name: "auto key outside a job", key: "auto", payload: testReleasePayload, noStep: true, want: "cbrgm/bluesky-github-action@v1.2.0"},
+		{name: "auto key without tag", key: "auto", payload: `{}`, ref: "refs/heads/main", want: ""},
+		{name: "idempotency disabled", key: "none", payload: testReleasePayload, want: ""},
+		{name: "empty key", key: "", payload: testReleasePayload, want: ""},
+		{name: "invalid event payload", key: "auto", payload: `{"release": `, wantErr: true},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useGitHubContext(t, tc.payload)
+			if tc.ref != "" {
+				t.Setenv("GITHUB_REF", tc.ref)
+				t.Setenv("GITHUB_REF_NAME", tc.ref[strings.LastIndex(tc.ref, "/")+1:])
+			}
+			if tc.step != "" {
+				t.Setenv("GITHUB_ACTION", tc.step)
+			}
+			if tc.noStep {
+				t.Setenv("GITHUB_JOB", "")
+				t.Setenv("GITHUB_ACTION", "")
+			}
+
+			got, err := resolveIdempotencyKey(tc.key, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("resolveIdempotencyKey() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("resolveIdempotencyKey() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindExistingPost(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:abc123"}
+	rkey := idempotencyRecordKey("release-v1")
+
+	tests := []struct {
+		name           string
+		args           ActionInputs
+		mockStatusCode int
+		mockResponse   string
+		wantRKey       string
+		wantExisting   bool
+		wantErr        bool
+	}{
+		{
+			name: "idempotency disabled",
+			args: ActionInputs{IdempotencyKey: "none", IdempotencyMode: "skip"},
+		},
+		{
+			name:           "existing post",
+			args:           ActionInputs{IdempotencyKey: "release-v1", IdempotencyMode: "skip"},
+			mockStatusCode: http.StatusOK,
+			mockResponse:   `{"uri": "at://did:plc:abc123/app.bsky.feed.post/` + rkey + `", "cid": "bafyreiexisting", "value": {}}`,
+			wantRKey:       rkey,
+			wantExisting:   true,
+		},
+		{
+			name:           "record not found",
+			args:           ActionInputs{IdempotencyKey: "release-v1", IdempotencyMode: "update"},
+			mockStatusCode: http.StatusBadRequest,
+			mockResponse:   `{"error": "RecordNotFound", "message": "Could not locate record"}`,
+			wantRKey:       rkey,
+		},
+		{
+			name:           "lookup failure",
+			args:           ActionInputs{IdempotencyKey: "release-v1", IdempotencyMode: "skip"},
+			mockStatusCode: http.StatusBadRequest,
+			mockResponse:   `{"error": "InvalidRequest"}`,
+			wantErr:        true,
+		},
+		{
+			name:    "invalid mode",
+			args:    ActionInputs{IdempotencyKey: "release-v1", IdempotencyMode: "replace"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				if r.URL.Path != "/xrpc/com.atproto.repo.getRecord" || query.Get("repo") != "did:plc:abc123" || query.Get("collection") != "app.bsky.feed.post" || query.Get("rkey") != rkey {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				w.WriteHeader(tc.mockStatusCode)
+				w.Write([]byte(tc.mockResponse))
+			}))
+			defer mockServer.Close()
+			tc.args.PDSURL = mockServer.URL
+
+			gotRKey, existing, err := findExistingPost(tc.args, session, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("findExistingPost() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if gotRKey != tc.wantRKey {
+				t.Errorf("findExistingPost() rkey = %q, want %q", gotRKey, tc.wantRKey)
+			}
+			if (existing != nil) != tc.wantExisting {
+				t.Errorf("findExistingPost() existing = %+v, want existing %v", existing, tc.wantExisting)
+			}
+			if existing != nil && existing.CID != "bafyreiexisting" {
+				t.Errorf("findExistingPost() CID = %q, want bafyreiexisting", existing.CID)
+			}
+		})
+	}
+}
+
+func TestPublishKeyedPost(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:abc123"}
+	post := &Post{Type: "app.bsky.feed.post", Text: "Release v1", CreatedAt: "2024-05-01T00:00:00Z"}
+
+	tests := []struct {
+		name       string
+		rkey       string
+		existing   *StrongRef
+		wantMethod string
+		wantBody   map[string]interface{}
+	}{
+		{
+			name:       "server-generated record key",
+			wantMethod: "com.atproto.repo.createRecord",
+			wantBody:   map[string]interface{}{"repo": "did:plc:abc123", "collection": "app.bsky.feed.post"},
+		},
+		{
+			name:       "new post with derived record key",
+			rkey:       "3jzfcijpj2z2a",
+			wantMethod: "com.atproto.repo.createRecord",
+			wantBody:   map[string]interface{}{"repo": "did:plc:abc123", "collection": "app.bsky.feed.post", "rkey": "3jzfcijpj2z2a"},
+		},
+		{
+			name:       "existing post is replaced",
+			rkey:       "3jzfcijpj2z2a",
+			existing:   &StrongRef{URI: "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a", CID: "bafyreiexisting"},
+			wantMethod: "com.atproto.repo.putRecord",
+			wantBody:   map[string]interface{}{"repo": "did:plc:abc123", "collection": "app.bsky.feed.post", "rkey": "3jzfcijpj2z2a", "swapRecord": "bafyreiexisting"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotMethod string
+			var gotBody map[string]interface{}
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod = strings.TrimPrefix(r.URL.Path, "/xrpc/")
+				json.NewDecoder(r.Body).Decode(&gotBody)
+				w.Write([]byte(`{"uri": "at://did:plc:abc123/app.bsky.feed.post/3jzfcijpj2z2a", "cid": "bafyreinew"}`))
+			}))
+			defer mockServer.Close()
+
+			record, err := publishKeyedPost(mockServer.URL, session, post, tc.rkey, tc.existing, logger)
+			if err != nil {
+				t.Fatalf("publishKeyedPost() unexpected error = %v", err)
+			}
+			if record.CID != "bafyreinew" {
+				t.Errorf("publishKeyedPost() CID = %q, want bafyreinew", record.CID)
+			}
+			if gotMethod != tc.wantMethod {
+				t.Errorf("publishKeyedPost() method = %s, want %s", gotMethod, tc.wantMethod)
+			}
+
+			delete(gotBody, "record")
+			if len(gotBody) != len(tc.wantBody) {
+				t.Errorf("publishKeyedPost() body = %v, want %v", gotBody, tc.wantBody)
+			}
+			for key, want := range tc.wantBody {
+				if gotBody[key] != want {
+					t.Errorf("publishKeyedPost() body[%s] = %v, want %v", key, gotBody[key], want)
+				}
+			}
+		})
+	}
+}
@@ -2,23 +2,49 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"fmt"
-	"image"
-	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // Constants for image upload constraints.
 const (
-	maxImagesPerPost = 4
-	maxImageSize     = 1000000 // 1MB in bytes
+	maxImagesPerPost     = 4
+	maxImageSize         = 1000000 // 1MB in bytes
+	maxConcurrentUploads = 4       // Upper bound of parallel image uploads.
 )
 
+// Policies for handling more images than fit into a single post.
+const (
+	imageOverflowError = "error"
+	imageOverflowReply = "reply"
+)
+
+// ImageOptions holds settings for processing images before upload.
+type ImageOptions struct {
+	AutoResize     bool          // Downscale and re-encode images that exceed maxImageSize.
+	MaxDimension   int           // Maximum width or height of a resized image.
+	StrictMimeType bool          // Reject images whose content does not match their file extension.
+	StripMetadata  bool          // Remove EXIF, XMP, IPTC, and text metadata before upload.
+	MediaManifest  string        // YAML or JSON file listing the images, replacing paths and alt texts.
+	AltSidecars    bool          // Read alt texts from "<image>.alt.txt" files next to the images.
+	AltText        AltTextPolicy // Handling of missing and overlong alt texts.
+	GitHubToken    string        // Token sent when downloading remote images from GitHub.
+}
+
+// ImageItem describes an image to attach to a post.
+type ImageItem struct {
+	Path        string       // Image file path.
+	Alt         string       // Alt text for the image.
+	AspectRatio *AspectRatio // Optional override of the detected aspect ratio.
+}
+
 // detectImageMimeType detects the MIME type based on file extension.
 func detectImageMimeType(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -31,20 +57,95 @@ func detectImageMimeType(filename string) string {
 		return "image/gif"
 	case ".webp":
 		return "image/webp"
+	case ".avif":
+		return "image/avif"
+	case ".heic", ".heif":
+		return "image/heic"
 	default:
 		return ""
 	}
 }
 
+// sniffImageMimeType detects the MIME type from the magic bytes at the start of the image data.
+func sniffImageMimeType(imageData []byte) string {
+	switch {
+	case bytes.HasPrefix(imageData, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(imageData, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(imageData, []byte("GIF87a")), bytes.HasPrefix(imageData, []byte("GIF89a")):
+		return "image/gif"
+	case len(imageData) >= 12 && string(imageData[0:4]) == "RIFF" && string(imageData[8:12]) == "WEBP":
+		return "image/webp"
+	case len(imageData) >= 12 && string(imageData[4:8]) == "ftyp":
+		return sniffISOBMFFImageType(imageData)
+	default:
+		return ""
+	}
+}
+
+// sniffISOBMFFImageType detects AVIF and HEIC images from the brands listed in
+// the ftyp box of an ISO base media file.
+func sniffISOBMFFImageType(imageData []byte) string {
+	boxSize := int(binary.BigEndian.Uint32(imageData[0:4]))
+	if boxSize < 16 || boxSize > len(imageData) {
+		boxSize = min(len(imageData), 64)
+	}
+
+	// The major brand is followed by a minor version and the compatible brands.
+	brands := []string{string(imageData[8:12])}
+	for offset := 16; offset+4 <= boxSize; offset += 4 {
+		brands = append(brands, string(imageData[offset:offset+4]))
+	}
+
+	for _, brand := range brands {
+		switch brand {
+		case "avif", "avis":
+			return "image/avif"
+		}
+	}
+	for _, brand := range brands {
+		switch brand {
+		case "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1":
+			return "image/heic"
+		}
+	}
+
+	return ""
+}
+
+// resolveImageMimeType determines the MIME type of an image from its content.
+// A file extension that disagrees with the content is corrected with a warning,
+// or rejected when strict is set.
+func resolveImageMimeType(path string, imageData []byte, strict bool, logger *slog.Logger) (string, error) {
+	sniffed := sniffImageMimeType(imageData)
+	if sniffed == "" {
+		return "", fmt.Errorf("unsupported image format for file %s (supported: JPEG, PNG, GIF, WebP, AVIF, HEIC)", path)
+	}
+
+	extType := detectImageMimeType(path)
+	if extType != "" && extType != sniffed {
+		if strict {
+			return "", fmt.Errorf("image %s has extension for %s but content is %s", path, extType, sniffed)
+		}
+		logger.Warn("Image extension does not match content, using detected type",
+			"path", path,
+			"extensionType", extType,
+			"detectedType", sniffed,
+		)
+	}
+
+	return sniffed, nil
+}
+
 // validateImageData validates image file size and format.
 func validateImageData(path string, imageData []byte) error {
 	if len(imageData) > maxImageSize {
 		return fmt.Errorf("image %s exceeds maximum size of %d bytes (got %d bytes)", path, maxImageSize, len(imageData))
 	}
 
-	mimeType := detectImageMimeType(path)
-	if mimeType == "" {
-		return fmt.Errorf("unsupported image format for file %s (supported: JPEG, PNG, GIF, WebP)", path)
+	if sniffImageMimeType(imageData) == "" {
+		return fmt.Errorf("unsupported image format for file %s (supported: JPEG, PNG, GIF, WebP, AVIF, HEIC)", path)
 	}
 
 	return nil
@@ -52,21 +153,21 @@ func validateImageData(path string, imageData []byte) error {
 
 // getImageDimensions extracts image dimensions for aspect ratio calculation.
 func getImageDimensions(imageData []byte, logger *slog.Logger) *AspectRatio {
-	config, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	width, height, err := decodeImageDimensions(imageData)
 	if err != nil {
 		logger.Debug("Could not determine image dimensions", "err", err)
 		return nil
 	}
 
-	logger.Debug("Image dimensions", "width", config.Width, "height", config.Height)
+	logger.Debug("Image dimensions", "width", width, "height", height)
 	return &AspectRatio{
-		Width:  config.Width,
-		Height: config.Height,
+		Width:  width,
+		Height: height,
 	}
 }
 
-// resolveAltText determines the appropriate alt text for an image.
-func resolveAltText(index int, altTexts []string) string {
+// explicitAltText returns the alt text given for an image, or an empty string if there is none.
+func explicitAltText(index int, altTexts []string) string {
 	// If we have a specific alt text for this index, use it
 	if index < len(altTexts) && strings.TrimSpace(altTexts[index]) != "" {
 		return strings.TrimSpace(altTexts[index])
@@ -77,10 +178,29 @@ func resolveAltText(index int, altTexts []string) string {
 		return strings.TrimSpace(altTexts[0])
 	}
 
+	return ""
+}
+
+// resolveAltText determines the appropriate alt text for an image.
+func resolveAltText(index int, altTexts []string) string {
+	if alt := explicitAltText(index, altTexts); alt != "" {
+		return alt
+	}
+
 	// Default alt text
 	return fmt.Sprintf("Image %d", index+1)
 }
 
+// readAltSidecar returns the alt text stored in the "<image>.alt.txt" file next
+// to an image, or an empty string if there is none.
+func readAltSidecar(path string) string {
+	data, err := os.ReadFile(path + ".alt.txt")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // parseImagePaths splits and trims image paths from comma-separated input.
 func parseImagePaths(imagePaths string) []string {
 	if imagePaths == "" {
@@ -98,26 +218,121 @@ func parseImagePaths(imagePaths string) []string {
 	return validPaths
 }
 
-// processImage processes a single image file: reads, validates, uploads, and creates an embed.
-func processImage(pdsURL, accessToken, path, altText string, logger *slog.Logger) (*EmbedImage, error) {
-	logger.Debug("Processing image", "path", path, "alt", altText)
+// expandImagePaths expands glob patterns and directories into image file paths.
+// Matches of each entry are sorted by name so the resulting order is deterministic;
+// directories contribute the files with a known image extension.
+func expandImagePaths(paths []string) ([]string, error) {
+	var expanded []string
+
+	for _, path := range paths {
+		// Remote images are downloaded when processed.
+		if isRemoteMedia(path) {
+			expanded = append(expanded, path)
+			continue
+		}
+
+		if strings.ContainsAny(path, "*?[") {
+			matches, err := filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image path pattern %s: %w", path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("image path pattern %s matched no files", path)
+			}
+			sort.Strings(matches)
+			expanded = append(expanded, matches...)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when the image is read.
+			expanded = append(expanded, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image directory %s: %w", path, err)
+		}
+
+		var found int
+		for _, entry := range entries {
+			if entry.IsDir() || detectImageMimeType(entry.Name()) == "" {
+				continue
+			}
+			expanded = append(expanded, filepath.Join(path, entry.Name()))
+			found++
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("image directory %s contains no images", path)
+		}
+	}
+
+	return expanded, nil
+}
+
+// readImageSource reads a local image file or downloads a remote image URL.
+func readImageSource(ctx context.Context, path string, opts ImageOptions, logger *slog.Logger) ([]byte, error) {
+	if isRemoteMedia(path) {
+		imageData, _, err := downloadMedia(ctx, path, "image", maxRemoteImageSize, opts.GitHubToken, logger)
+		return imageData, err
+	}
 
-	// Read image file
 	imageData, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image file %s: %w", path, err)
 	}
+	return imageData, nil
+}
+
+// processImage processes a single image file: reads, validates, uploads, and creates an embed.
+func processImage(ctx context.Context, pdsURL, accessToken, path, altText string, opts ImageOptions, logger *slog.Logger) (*EmbedImage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Processing image", "path", path, "alt", altText)
+
+	// Read image file or download remote image
+	imageData, err := readImageSource(ctx, path, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Detect image type from content
+	mimeType, err := resolveImageMimeType(path, imageData, opts.StrictMimeType, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove metadata such as GPS coordinates if enabled
+	if opts.StripMetadata {
+		imageData, err = stripUploadMetadata(path, imageData, mimeType, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Shrink oversized images if enabled
+	if opts.AutoResize && len(imageData) > maxImageSize {
+		logger.Info("Image exceeds size limit, resizing", "path", path, "size", len(imageData))
+		resized, resizedType, err := fitImageToLimit(imageData, opts.MaxDimension, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resize image %s: %w", path, err)
+		}
+		imageData, mimeType = resized, resizedType
+	}
 
 	// Validate image
 	if err := validateImageData(path, imageData); err != nil {
 		return nil, err
 	}
 
-	mimeType := detectImageMimeType(path)
 	logger.Debug("Uploading image blob", "path", path, "size", len(imageData), "mimeType", mimeType)
 
 	// Upload blob
-	blob, err := uploadBlob(pdsURL, accessToken, imageData, mimeType, logger)
+	blob, err := uploadBlobContext(ctx, pdsURL, accessToken, imageData, mimeType, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload image %s: %w", path, err)
 	}
@@ -132,30 +347,110 @@ func processImage(pdsURL, accessToken, path, altText string, logger *slog.Logger
 	}, nil
 }
 
-// processImages reads image files, uploads them as blobs, and creates an EmbedImages structure.
-func processImages(pdsURL, accessToken, imagePaths, altTexts string, logger *slog.Logger) (*EmbedImages, error) {
-	paths := parseImagePaths(imagePaths)
-	if len(paths) == 0 {
-		return nil, nil
+// collectImageItems determines the images to attach and their alt texts, either
+// from the media manifest or from the comma-separated paths and alt texts.
+// Alt text sidecar files take precedence over comma-separated alt texts.
+func collectImageItems(imagePaths, altTexts string, opts ImageOptions) ([]ImageItem, error) {
+	if opts.MediaManifest != "" {
+		return loadMediaManifest(opts.MediaManifest, opts.AltSidecars)
 	}
 
-	// Validate image count
-	if len(paths) > maxImagesPerPost {
-		return nil, fmt.Errorf("maximum %d images allowed per post, got %d", maxImagesPerPost, len(paths))
+	paths, err := expandImagePaths(parseImagePaths(imagePaths))
+	if err != nil {
+		return nil, err
 	}
 
 	alts := strings.Split(altTexts, ",")
-	var embedImages []EmbedImage
+	var items []ImageItem
 
 	for i, path := range paths {
-		altText := resolveAltText(i, alts)
+		alt := ""
+		if opts.AltSidecars && !isRemoteMedia(path) {
+			alt = readAltSidecar(path)
+		}
+		if alt == "" {
+			alt = explicitAltText(i, alts)
+		}
+
+		items = append(items, ImageItem{Path: path, Alt: alt})
+	}
 
-		embedImage, err := processImage(pdsURL, accessToken, path, altText, logger)
+	return items, nil
+}
+
+// resolveImageItems collects the images to attach and applies the alt text
+// policy to them before anything is uploaded.
+func resolveImageItems(imagePaths, altTexts string, opts ImageOptions, logger *slog.Logger) ([]ImageItem, error) {
+	items, err := collectImageItems(imagePaths, altTexts, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		alt, err := applyAltTextPolicy(items[i].Alt, resolveAltText(i, nil), items[i].Path, maxImageAltTextGraphemes, opts.AltText, logger)
 		if err != nil {
 			return nil, err
 		}
+		items[i].Alt = alt
+	}
+
+	return items, nil
+}
+
+// processImageGroup uploads a group of images concurrently and creates an
+// EmbedImages structure with the images in their original order. The first
+// failure cancels all uploads still in progress.
+func processImageGroup(pdsURL, accessToken string, items []ImageItem, opts ImageOptions, logger *slog.Logger) (*EmbedImages, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	embedImages := make([]EmbedImage, len(items))
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	workers := min(maxConcurrentUploads, len(items))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item := items[i]
+				embedImage, err := processImage(ctx, pdsURL, accessToken, item.Path, item.Alt, opts, logger)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+
+				if item.AspectRatio != nil {
+					embedImage.AspectRatio = item.AspectRatio
+				}
+
+				embedImages[i] = *embedImage
+			}
+		}()
+	}
+
+feed:
+	for i := range items {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		embedImages = append(embedImages, *embedImage)
+	if firstErr != nil {
+		return nil, firstErr
 	}
 
 	return &EmbedImages{
@@ -163,3 +458,46 @@ func processImages(pdsURL, accessToken, imagePaths, altTexts string, logger *slo
 		Images: embedImages,
 	}, nil
 }
+
+// processImages reads image files, uploads them as blobs, and creates an EmbedImages structure.
+func processImages(pdsURL, accessToken, imagePaths, altTexts string, opts ImageOptions, logger *slog.Logger) (*EmbedImages, error) {
+	items, err := resolveImageItems(imagePaths, altTexts, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	// Validate image count
+	if len(items) > maxImagesPerPost {
+		return nil, fmt.Errorf("maximum %d images allowed per post, got %d", maxImagesPerPost, len(items))
+	}
+
+	return processImageGroup(pdsURL, accessToken, items, opts, logger)
+}
+
+// processImageGroups reads image files and uploads them in groups of at most
+// maxImagesPerPost, one EmbedImages structure per post. Groups after the first
+// are meant to be attached to replies.
+func processImageGroups(pdsURL, accessToken, imagePaths, altTexts string, opts ImageOptions, logger *slog.Logger) ([]*EmbedImages, error) {
+	items, err := resolveImageItems(imagePaths, altTexts, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*EmbedImages
+
+	for start := 0; start < len(items); start += maxImagesPerPost {
+		end := min(start+maxImagesPerPost, len(items))
+
+		group, err := processImageGroup(pdsURL, accessToken, items[start:end], opts, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestDetectImageMimeType(t *testing.T) {
@@ -43,6 +48,16 @@ func TestDetectImageMimeType(t *testing.T) {
 			filename: "photo.webp",
 			want:     "image/webp",
 		},
+		{
+			name:     "AVIF file",
+			filename: "photo.avif",
+			want:     "image/avif",
+		},
+		{
+			name:     "HEIC file",
+			filename: "photo.HEIC",
+			want:     "image/heic",
+		},
 		{
 			name:     "unsupported file type",
 			filename: "document.pdf",
@@ -65,6 +80,161 @@ func TestDetectImageMimeType(t *testing.T) {
 	}
 }
 
+// Magic bytes used to build image fixtures.
+var (
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	pngMagic  = []byte("\x89PNG\r\n\x1a\n")
+	gifMagic  = []byte("GIF89a")
+	webpMagic = []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")
+	avifMagic = []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf")
+	heicMagic = []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic")
+)
+
+// fakeImageData returns size bytes that start with the given magic bytes.
+func fakeImageData(magic []byte, size int) []byte {
+	data := make([]byte, size)
+	copy(data, magic)
+	return data
+}
+
+func TestSniffImageMimeType(t *testing.T) {
+	tests := []struct {
+		name      string
+		imageData []byte
+		want      string
+	}{
+		{
+			name:      "JPEG",
+			imageData: fakeImageData(jpegMagic, 64),
+			want:      "image/jpeg",
+		},
+		{
+			name:      "PNG",
+			imageData: fakeImageData(pngMagic, 64),
+			want:      "image/png",
+		},
+		{
+			name:      "GIF",
+			imageData: fakeImageData(gifMagic, 64),
+			want:      "image/gif",
+		},
+		{
+			name:      "WebP",
+			imageData: fakeImageData(webpMagic, 64),
+			want:      "image/webp",
+		},
+		{
+			name:      "AVIF",
+			imageData: fakeImageData(avifMagic, 64),
+			want:      "image/avif",
+		},
+		{
+			name:      "HEIC",
+			imageData: fakeImageData(heicMagic, 64),
+			want:      "image/heic",
+		},
+		{
+			name:      "AVIF listed only as compatible brand",
+			imageData: fakeImageData([]byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1avif"), 64),
+			want:      "image/avif",
+		},
+		{
+			name:      "MP4 video is not an image",
+			imageData: fakeImageData([]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"), 64),
+			want:      "",
+		},
+		{
+			name:      "RIFF without WEBP",
+			imageData: fakeImageData([]byte("RIFF\x00\x00\x00\x00WAVE"), 64),
+			want:      "",
+		},
+		{
+			name:      "PDF document",
+			imageData: []byte("%PDF-1.7"),
+			want:      "",
+		},
+		{
+			name:      "empty data",
+			imageData: nil,
+			want:      "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := sniffImageMimeType(tc.imageData)
+			if got != tc.want {
+				t.Errorf("sniffImageMimeType() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveImageMimeType(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name      string
+		path      string
+		imageData []byte
+		strict    bool
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "matching extension",
+			path:      "photo.jpg",
+			imageData: fakeImageData(jpegMagic, 64),
+			want:      "image/jpeg",
+		},
+		{
+			name:      "mismatched extension is corrected",
+			path:      "photo.png",
+			imageData: fakeImageData(jpegMagic, 64),
+			want:      "image/jpeg",
+		},
+		{
+			name:      "mismatched extension is rejected in strict mode",
+			path:      "photo.png",
+			imageData: fakeImageData(jpegMagic, 64),
+			strict:    true,
+			wantErr:   true,
+		},
+		{
+			name:      "no extension",
+			path:      "photo",
+			imageData: fakeImageData(pngMagic, 64),
+			strict:    true,
+			want:      "image/png",
+		},
+		{
+			name:      "unknown extension",
+			path:      "photo.bin",
+			imageData: fakeImageData(webpMagic, 64),
+			strict:    true,
+			want:      "image/webp",
+		},
+		{
+			name:      "unrecognized content",
+			path:      "photo.png",
+			imageData: make([]byte, 64),
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveImageMimeType(tc.path, tc.imageData, tc.strict, logger)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("resolveImageMimeType() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("resolveImageMimeType() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestResolveAltText(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -185,13 +355,13 @@ func TestValidateImageData(t *testing.T) {
 		{
 			name:      "valid PNG image under size limit",
 			path:      "test.png",
-			imageData: make([]byte, 500000),
+			imageData: fakeImageData(pngMagic, 500000),
 			wantErr:   false,
 		},
 		{
 			name:      "valid JPEG image at size limit",
 			path:      "test.jpg",
-			imageData: make([]byte, maxImageSize),
+			imageData: fakeImageData(jpegMagic, maxImageSize),
 			wantErr:   false,
 		},
 		{
@@ -211,15 +381,28 @@ func TestValidateImageData(t *testing.T) {
 		{
 			name:      "valid GIF image",
 			path:      "animation.gif",
-			imageData: make([]byte, 100000),
+			imageData: fakeImageData(gifMagic, 100000),
 			wantErr:   false,
 		},
 		{
 			name:      "valid WebP image",
 			path:      "photo.webp",
-			imageData: make([]byte, 100000),
+			imageData: fakeImageData(webpMagic, 100000),
+			wantErr:   false,
+		},
+		{
+			name:      "valid image without extension",
+			path:      "screenshot",
+			imageData: fakeImageData(pngMagic, 1000),
 			wantErr:   false,
 		},
+		{
+			name:      "unrecognized content with image extension",
+			path:      "fake.png",
+			imageData: make([]byte, 1000),
+			wantErr:   true,
+			errMsg:    "unsupported image format",
+		},
 	}
 
 	for _, tc := range tests {
@@ -347,7 +530,7 @@ func TestProcessImages(t *testing.T) {
 			mockServer := tc.setupMock()
 			defer mockServer.Close()
 
-			result, err := processImages(mockServer.URL, "fake-token", tc.imagePaths, tc.altTexts, logger)
+			result, err := processImages(mockServer.URL, "fake-token", tc.imagePaths, tc.altTexts, ImageOptions{}, logger)
 
 			if (err != nil) != tc.wantErr {
 				t.Errorf("processImages() error = %v, wantErr %v", err, tc.wantErr)
@@ -414,7 +597,7 @@ func TestProcessImagesMaxCount(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	defer mockServer.Close()
 
-	_, err = processImages(mockServer.URL, "fake-token", fiveImages, "Test", logger)
+	_, err = processImages(mockServer.URL, "fake-token", fiveImages, "Test", ImageOptions{}, logger)
 	if err == nil {
 		t.Error("processImages() expected error for more than 4 images, got nil")
 	}
@@ -440,8 +623,386 @@ func TestProcessImagesMaxSize(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
 	defer mockServer.Close()
 
-	_, err = processImages(mockServer.URL, "fake-token", largeImagePath, "Test", logger)
+	_, err = processImages(mockServer.URL, "fake-token", largeImagePath, "Test", ImageOptions{}, logger)
 	if err == nil {
 		t.Error("processImages() expected error for image larger than 1MB, got nil")
 	}
 }
+
+func TestProcessImagesAutoResize(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	largeImagePath := filepath.Join(tempDir, "screenshot.png")
+	if err := os.WriteFile(largeImagePath, noisePNG(t, 1000, 600, true), 0644); err != nil {
+		t.Fatalf("Failed to write large image: %v", err)
+	}
+
+	var uploadedType string
+	var uploadedSize int
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploadedType = r.Header.Get("Content-Type")
+		uploadedSize = len(body)
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type": "blob",
+				"ref": map[string]interface{}{
+					"$link": "bafkreibabalobzn6cd366ukcsjycp4yymjymgfxcv6xczmlgpemzkz3cfa",
+				},
+				"mimeType": uploadedType,
+				"size":     uploadedSize,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	result, err := processImages(mockServer.URL, "fake-token", largeImagePath, "Screenshot", ImageOptions{AutoResize: true, MaxDimension: 800}, logger)
+	if err != nil {
+		t.Fatalf("processImages() unexpected error = %v", err)
+	}
+
+	if uploadedType != "image/jpeg" {
+		t.Errorf("uploaded Content-Type = %s, want image/jpeg", uploadedType)
+	}
+	if uploadedSize > maxImageSize {
+		t.Errorf("uploaded size = %d, want at most %d", uploadedSize, maxImageSize)
+	}
+
+	aspectRatio := result.Images[0].AspectRatio
+	if aspectRatio == nil || aspectRatio.Width != 800 || aspectRatio.Height != 480 {
+		t.Errorf("processImages() aspectRatio = %+v, want 800x480", aspectRatio)
+	}
+}
+
+func TestExpandImagePaths(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	screenshotDir := filepath.Join(tempDir, "screenshots")
+	if err := os.MkdirAll(filepath.Join(screenshotDir, "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create screenshot dir: %v", err)
+	}
+	for _, name := range []string{"c.png", "a.png", "b.jpg", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(screenshotDir, name), fakeImageData(pngMagic, 64), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	emptyDir := filepath.Join(tempDir, "empty")
+	if err := os.Mkdir(emptyDir, 0755); err != nil {
+		t.Fatalf("Failed to create empty dir: %v", err)
+	}
+
+	join := func(name string) string { return filepath.Join(screenshotDir, name) }
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "glob pattern sorted by name",
+			paths: []string{filepath.Join(screenshotDir, "*.png")},
+			want:  []string{join("a.png"), join("c.png")},
+		},
+		{
+			name:  "directory with images only",
+			paths: []string{screenshotDir},
+			want:  []string{join("a.png"), join("b.jpg"), join("c.png")},
+		},
+		{
+			name:  "literal paths keep given order",
+			paths: []string{join("c.png"), join("a.png")},
+			want:  []string{join("c.png"), join("a.png")},
+		},
+		{
+			name:  "mixed entries keep entry order",
+			paths: []string{join("b.jpg"), filepath.Join(screenshotDir, "*.png")},
+			want:  []string{join("b.jpg"), join("a.png"), join("c.png")},
+		},
+		{
+			name:  "missing literal path is passed through",
+			paths: []string{"/nonexistent/image.png"},
+			want:  []string{"/nonexistent/image.png"},
+		},
+		{
+			name:    "pattern without matches",
+			paths:   []string{filepath.Join(screenshotDir, "*.gif")},
+			wantErr: true,
+		},
+		{
+			name:    "directory without images",
+			paths:   []string{emptyDir},
+			wantErr: true,
+		},
+		{
+			name:    "malformed pattern",
+			paths:   []string{filepath.Join(screenshotDir, "[")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandImagePaths(tc.paths)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expandImagePaths() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expandImagePaths() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expandImagePaths()[%d] = %s, want %s", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessImageGroups(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for i := 1; i <= 6; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("shot-%d.png", i))
+		if err := os.WriteFile(path, fakeImageData(pngMagic, 64), 0644); err != nil {
+			t.Fatalf("Failed to write test image: %v", err)
+		}
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]interface{}{"$link": "bafkreishot"},
+				"mimeType": "image/png",
+				"size":     64,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	pattern := filepath.Join(tempDir, "*.png")
+
+	t.Run("spills extra images into further groups", func(t *testing.T) {
+		groups, err := processImageGroups(mockServer.URL, "fake-token", pattern, "One,Two,Three,Four,Five,Six", ImageOptions{}, logger)
+		if err != nil {
+			t.Fatalf("processImageGroups() unexpected error = %v", err)
+		}
+		if len(groups) != 2 {
+			t.Fatalf("processImageGroups() returned %d groups, want 2", len(groups))
+		}
+		if len(groups[0].Images) != 4 || len(groups[1].Images) != 2 {
+			t.Errorf("processImageGroups() group sizes = %d, %d, want 4, 2", len(groups[0].Images), len(groups[1].Images))
+		}
+		if groups[1].Images[0].Alt != "Five" || groups[1].Images[1].Alt != "Six" {
+			t.Errorf("processImageGroups() second group alts = %s, %s, want Five, Six", groups[1].Images[0].Alt, groups[1].Images[1].Alt)
+		}
+	})
+
+	t.Run("single post rejects too many expanded images", func(t *testing.T) {
+		if _, err := processImages(mockServer.URL, "fake-token", pattern, "", ImageOptions{}, logger); err == nil {
+			t.Error("processImages() expected error for more than 4 expanded images, got nil")
+		}
+	})
+}
+
+func TestResolveImageItemsSidecars(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	first := filepath.Join(tempDir, "first.png")
+	second := filepath.Join(tempDir, "second.png")
+	if err := os.WriteFile(first+".alt.txt", []byte("  Chart of build times, by week\n"), 0644); err != nil {
+		t.Fatalf("Failed to write sidecar: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		useSidecars bool
+		altTexts    string
+		want        []string
+	}{
+		{
+			name:        "sidecar takes precedence, comma-split alt as fallback",
+			useSidecars: true,
+			altTexts:    "Inline first,Inline second",
+			want:        []string{"Chart of build times, by week", "Inline second"},
+		},
+		{
+			name:        "sidecars ignored unless enabled",
+			useSidecars: false,
+			altTexts:    "Inline first,Inline second",
+			want:        []string{"Inline first", "Inline second"},
+		},
+		{
+			name:        "default alt when neither is given",
+			useSidecars: true,
+			altTexts:    "",
+			want:        []string{"Chart of build times, by week", "Image 2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := resolveImageItems(first+","+second, tc.altTexts, ImageOptions{AltSidecars: tc.useSidecars}, logger)
+			if err != nil {
+				t.Fatalf("resolveImageItems() unexpected error = %v", err)
+			}
+			if len(items) != len(tc.want) {
+				t.Fatalf("resolveImageItems() returned %d items, want %d", len(items), len(tc.want))
+			}
+			for i, item := range items {
+				if item.Alt != tc.want[i] {
+					t.Errorf("resolveImageItems()[%d] alt = %q, want %q", i, item.Alt, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// writeTaggedImages writes PNG files whose content ends with a unique tag, so
+// a mock server can tell the uploads apart.
+func writeTaggedImages(t *testing.T, dir string, tags []string) []string {
+	t.Helper()
+
+	var paths []string
+	for _, tag := range tags {
+		path := filepath.Join(dir, tag+".png")
+		data := append(fakeImageData(pngMagic, 64), []byte("tag:"+tag)...)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write test image: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// uploadTag extracts the tag written by writeTaggedImages from an upload.
+func uploadTag(r *http.Request) string {
+	body, _ := io.ReadAll(r.Body)
+	if i := bytes.LastIndex(body, []byte("tag:")); i >= 0 {
+		return string(body[i+4:])
+	}
+	return ""
+}
+
+func TestProcessImagesParallelOrder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tags := []string{"first", "second", "third", "fourth"}
+	paths := writeTaggedImages(t, tempDir, tags)
+
+	var inFlight, maxInFlight atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+
+		tag := uploadTag(r)
+		time.Sleep(time.Duration(rand.Intn(40)) * time.Millisecond)
+
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]interface{}{"$link": "bafkrei" + tag},
+				"mimeType": "image/png",
+				"size":     64,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	for run := 0; run < 5; run++ {
+		result, err := processImages(mockServer.URL, "fake-token", strings.Join(paths, ","), strings.Join(tags, ","), ImageOptions{}, logger)
+		if err != nil {
+			t.Fatalf("processImages() unexpected error = %v", err)
+		}
+
+		if len(result.Images) != len(tags) {
+			t.Fatalf("processImages() returned %d images, want %d", len(result.Images), len(tags))
+		}
+		for i, tag := range tags {
+			if got := result.Images[i].Image.Ref.Link; got != "bafkrei"+tag {
+				t.Errorf("run %d: image %d blob = %s, want %s", run, i, got, "bafkrei"+tag)
+			}
+			if got := result.Images[i].Alt; got != tag {
+				t.Errorf("run %d: image %d alt = %s, want %s", run, i, got, tag)
+			}
+		}
+	}
+
+	if got := maxInFlight.Load(); got > maxConcurrentUploads {
+		t.Errorf("max concurrent uploads = %d, want at most %d", got, maxConcurrentUploads)
+	}
+}
+
+func TestProcessImagesCancelOnFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTaggedImages(t, tempDir, []string{"slow1", "broken", "slow2"})
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if uploadTag(r) == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"InternalServerError"}`))
+			return
+		}
+
+		// Slow uploads only finish early when the client cancels them.
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer mockServer.Close()
+
+	start := time.Now()
+	_, err = processImages(mockServer.URL, "fake-token", strings.Join(paths, ","), "Alt", ImageOptions{}, logger)
+	if err == nil || !strings.Contains(err.Error(), "broken.png") {
+		t.Fatalf("processImages() error = %v, want upload error for broken.png", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("processImages() took %v, want remaining uploads canceled", elapsed)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// LinkCardOptions holds user-provided settings for the link card embed.
+type LinkCardOptions struct {
+	URL           string // Explicit card URL, overrides the URL detected in the text.
+	Title         string // Explicit card title.
+	Description   string // Explicit card description.
+	ThumbnailPath string // Local image file used as the card thumbnail.
+	Selection     string // Policy for choosing the detected URL: first, last, match, or index.
+	Domains       string // Comma-separated domain allow-list for the match policy.
+	Pattern       string // Regular expression for the match policy.
+	Index         int    // 1-based position of the detected URL for the index policy.
+	StripMetadata bool   // Remove metadata such as GPS coordinates from the thumbnail.
+	PreferredURL  string // URL tried before the URLs detected in the text, such as a release page.
+}
+
+// hasOverride reports whether any explicit card field is set, in which case
+// the card is built directly instead of being fetched from the linked page.
+func (o LinkCardOptions) hasOverride() bool {
+	return o.URL != "" || o.Title != "" || o.Description != "" || o.ThumbnailPath != ""
+}
+
+// Link card selection policies.
+const (
+	linkCardSelectFirst = "first"
+	linkCardSelectLast  = "last"
+	linkCardSelectMatch = "match"
+	linkCardSelectIndex = "index"
+)
+
+// linkCardDefaultIndex is the default of the link-card-index input.
+const linkCardDefaultIndex = 1
+
+// matchesLinkCardFilter reports whether a URL's host is in the domain
+// allow-list (including subdomains) or the URL matches the pattern.
+func matchesLinkCardFilter(rawURL string, domains []string, pattern *regexp.Regexp) bool {
+	if pattern != nil && pattern.MatchString(rawURL) {
+		return true
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// linkCardSelection returns the selection policy of the options. A link card
+// index other than the default selects the index policy in place of first and
+// conflicts with the other policies.
+func linkCardSelection(opts LinkCardOptions) (string, error) {
+	if opts.Index == 0 || opts.Index == linkCardDefaultIndex {
+		return opts.Selection, nil
+	}
+
+	switch opts.Selection {
+	case "", linkCardSelectFirst, linkCardSelectIndex:
+		return linkCardSelectIndex, nil
+	default:
+		return "", fmt.Errorf("link card index %d requires link card selection %q, got %q", opts.Index, linkCardSelectIndex, opts.Selection)
+	}
+}
+
+// linkCardCandidates returns the distinct URLs detected in the text that may
+// become the link card, ordered by preference according to the selection
+// policy.
+func linkCardCandidates(facets []RichTextFacet, opts LinkCardOptions) ([]string, error) {
+	selection, err := linkCardSelection(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	for _, facet := range facets {
+		uri := facet.Features[0].URI
+		if !seen[uri] {
+			seen[uri] = true
+			urls = append(urls, uri)
+		}
+	}
+
+	switch selection {
+	case "", linkCardSelectFirst:
+		return urls, nil
+	case linkCardSelectLast:
+		reversed := make([]string, 0, len(urls))
+		for i := len(urls) - 1; i >= 0; i-- {
+			reversed = append(reversed, urls[i])
+		}
+		return reversed, nil
+	case linkCardSelectMatch:
+		domains := strings.Split(opts.Domains, ",")
+
+		var pattern *regexp.Regexp
+		if opts.Pattern != "" {
+			compiled, err := regexp.Compile(opts.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid link card pattern %q: %w", opts.Pattern, err)
+			}
+			pattern = compiled
+		}
+
+		if pattern == nil && strings.TrimSpace(opts.Domains) == "" {
+			return nil, fmt.Errorf("link card selection %q requires a domain allow-list or pattern", linkCardSelectMatch)
+		}
+
+		var matched []string
+		for _, uri := range urls {
+			if matchesLinkCardFilter(uri, domains, pattern) {
+				matched = append(matched, uri)
+			}
+		}
+		return matched, nil
+	case linkCardSelectIndex:
+		if len(urls) == 0 {
+			return nil, nil
+		}
+		if opts.Index < 1 || opts.Index > len(urls) {
+			return nil, fmt.Errorf("link card index %d out of range, post contains %d distinct URLs", opts.Index, len(urls))
+		}
+		return []string{urls[opts.Index-1]}, nil
+	default:
+		return nil, fmt.Errorf("unknown link card selection %q (supported: first, last, match, index)", opts.Selection)
+	}
+}
+
+// preferLinkCardURL moves the preferred URL, if any, to the front of the link
+// card candidates.
+func preferLinkCardURL(candidates []string, preferred string) []string {
+	preferred = strings.TrimSpace(preferred)
+	if preferred == "" {
+		return candidates
+	}
+	return append([]string{preferred}, slices.DeleteFunc(candidates, func(uri string) bool { return uri == preferred })...)
+}
+
+// uploadLinkCardThumbnail reads, validates, and uploads a local thumbnail
+// image, removing its metadata if enabled.
+func uploadLinkCardThumbnail(pdsURL, accessToken, path string, stripMetadata bool, logger *slog.Logger) (*Blob, error) {
+	thumbData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read link card thumbnail %s: %w", path, err)
+	}
+
+	if err := validateImageData(path, thumbData); err != nil {
+		return nil, err
+	}
+
+	mimeType, err := resolveImageMimeType(path, thumbData, false, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if stripMetadata {
+		thumbData, err = stripUploadMetadata(path, thumbData, mimeType, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Debug("Uploading link card thumbnail", "path", path, "size", len(thumbData), "mimeType", mimeType)
+
+	blob, err := uploadBlob(pdsURL, accessToken, thumbData, mimeType, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload link card thumbnail %s: %w", path, err)
+	}
+
+	return blob, nil
+}
+
+// buildLinkCard constructs an EmbedExternal from explicit options without
+// fetching the linked page.
+func buildLinkCard(pdsURL, accessToken, uri string, opts LinkCardOptions, logger *slog.Logger) (*EmbedExternal, error) {
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		title = uri
+	}
+
+	card := &EmbedExternal{
+		Type: "app.bsky.embed.external",
+		External: EmbedExternalContent{
+			URI:         uri,
+			Title:       title,
+			Description: strings.TrimSpace(opts.Description),
+		},
+	}
+
+	if opts.ThumbnailPath != "" {
+		thumb, err := uploadLinkCardThumbnail(pdsURL, accessToken, opts.ThumbnailPath, opts.StripMetadata, logger)
+		if err != nil {
+			return nil, err
+		}
+		card.External.Thumb = thumb
+	}
+
+	return card, nil
+}
+
+// processLinkCard determines the link card for a post. Explicit options take
+// precedence over metadata fetched from the preferred URL and the URLs in the
+// text. Candidates whose metadata cannot be fetched are skipped in favor of the
+// next one.
+func processLinkCard(pdsURL, accessToken string, facets []RichTextFacet, opts LinkCardOptions, enableEmbeds bool, logger *slog.Logger) (*EmbedExternal, error) {
+	if !enableEmbeds && !opts.hasOverride() {
+		return nil, nil
+	}
+
+	// The selection options are only used, and validated, without an explicit URL.
+	uri := strings.TrimSpace(opts.URL)
+	if uri == "" {
+		candidates, err := linkCardCandidates(facets, opts)
+		if err != nil {
+			return nil, err
+		}
+		candidates = preferLinkCardURL(candidates, opts.PreferredURL)
+
+		if !opts.hasOverride() {
+			for _, uri := range candidates {
+				logger.Debug("Fetching embed metadata", "url", uri)
+				if card := fetchLinkMetadata(uri, logger); card != nil {
+					return card, nil
+				}
+				logger.Info("Could not create link card, trying next URL", "url", uri)
+			}
+			return nil, nil
+		}
+
+		if len(candidates) == 0 {
+			logger.Warn("Link card options set but no URL found, skipping link card")
+			return nil, nil
+		}
+		uri = candidates[0]
+	}
+
+	logger.Debug("Building link card from explicit options", "url", uri)
+	return buildLinkCard(pdsURL, accessToken, uri, opts, logger)
+}
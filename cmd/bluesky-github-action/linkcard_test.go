@@ -0,0 +1,382 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkCardCandidates(t *testing.T) {
+	facets := parseRichTextFacets("Docs https://docs.example.com, code https://github.com/cbrgm/bluesky-github-action and release https://example.com/releases/v1")
+
+	tests := []struct {
+		name    string
+		facets  []RichTextFacet
+		opts    LinkCardOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "first policy keeps text order",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "first"},
+			want:   []string{"https://docs.example.com", "https://github.com/cbrgm/bluesky-github-action", "https://example.com/releases/v1"},
+		},
+		{
+			name:   "empty policy defaults to first",
+			facets: facets,
+			opts:   LinkCardOptions{},
+			want:   []string{"https://docs.example.com", "https://github.com/cbrgm/bluesky-github-action", "https://example.com/releases/v1"},
+		},
+		{
+			name:   "last policy reverses order",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "last"},
+			want:   []string{"https://example.com/releases/v1", "https://github.com/cbrgm/bluesky-github-action", "https://docs.example.com"},
+		},
+		{
+			name:   "match policy with exact domain",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "match", Domains: "github.com"},
+			want:   []string{"https://github.com/cbrgm/bluesky-github-action"},
+		},
+		{
+			name:   "match policy includes subdomains",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "match", Domains: " example.com "},
+			want:   []string{"https://docs.example.com", "https://example.com/releases/v1"},
+		},
+		{
+			name:   "match policy with pattern",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "match", Pattern: `/releases/`},
+			want:   []string{"https://example.com/releases/v1"},
+		},
+		{
+			name:   "match policy without matches",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "match", Domains: "gitlab.com"},
+			want:   nil,
+		},
+		{
+			name:    "match policy without filters",
+			facets:  facets,
+			opts:    LinkCardOptions{Selection: "match"},
+			wantErr: true,
+		},
+		{
+			name:    "match policy with invalid pattern",
+			facets:  facets,
+			opts:    LinkCardOptions{Selection: "match", Pattern: "("},
+			wantErr: true,
+		},
+		{
+			name:   "index policy",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "index", Index: 2},
+			want:   []string{"https://github.com/cbrgm/bluesky-github-action"},
+		},
+		{
+			name:    "index policy out of range",
+			facets:  facets,
+			opts:    LinkCardOptions{Selection: "index", Index: 4},
+			wantErr: true,
+		},
+		{
+			name:   "index policy without URLs",
+			facets: nil,
+			opts:   LinkCardOptions{Selection: "index", Index: 1},
+			want:   nil,
+		},
+		{
+			name:   "index policy counts duplicate URLs once",
+			facets: parseRichTextFacets("https://example.com, https://example.com and https://docs.example.com"),
+			opts:   LinkCardOptions{Selection: "index", Index: 2},
+			want:   []string{"https://docs.example.com"},
+		},
+		{
+			name:   "non-default index selects index policy",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "first", Index: 3},
+			want:   []string{"https://example.com/releases/v1"},
+		},
+		{
+			name:   "default index keeps first policy",
+			facets: facets,
+			opts:   LinkCardOptions{Selection: "first", Index: 1},
+			want:   []string{"https://docs.example.com", "https://github.com/cbrgm/bluesky-github-action", "https://example.com/releases/v1"},
+		},
+		{
+			name:    "non-default index conflicts with last policy",
+			facets:  facets,
+			opts:    LinkCardOptions{Selection: "last", Index: 2},
+			wantErr: true,
+		},
+		{
+			name:   "duplicate URLs are returned once",
+			facets: parseRichTextFacets("https://example.com and again https://example.com"),
+			opts:   LinkCardOptions{Selection: "first"},
+			want:   []string{"https://example.com"},
+		},
+		{
+			name:    "unknown policy",
+			facets:  facets,
+			opts:    LinkCardOptions{Selection: "random"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := linkCardCandidates(tc.facets, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("linkCardCandidates() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("linkCardCandidates() = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("linkCardCandidates()[%d] = %s, want %s", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProcessLinkCard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	thumbPath := filepath.Join(tempDir, "thumb.png")
+	if err := os.WriteFile(thumbPath, fakeImageData(pngMagic, 64), 0644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type": "blob",
+				"ref": map[string]interface{}{
+					"$link": "bafkreithumb",
+				},
+				"mimeType": "image/png",
+				"size":     13,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	facets := parseRichTextFacets("Docs https://docs.example.com and release https://example.com/releases/v1")
+
+	tests := []struct {
+		name         string
+		facets       []RichTextFacet
+		opts         LinkCardOptions
+		enableEmbeds bool
+		wantNil      bool
+		wantURI      string
+		wantTitle    string
+		wantThumb    bool
+		wantErr      bool
+	}{
+		{
+			name:   "explicit URL and title",
+			facets: facets,
+			opts: LinkCardOptions{
+				URL:         "https://example.com/custom",
+				Title:       "Custom title",
+				Description: "Custom description",
+				Index:       1,
+			},
+			wantURI:   "https://example.com/custom",
+			wantTitle: "Custom title",
+		},
+		{
+			name:   "explicit title for selected detected URL",
+			facets: facets,
+			opts: LinkCardOptions{
+				Title:     "Release v1",
+				Selection: "index",
+				Index:     2,
+			},
+			wantURI:   "https://example.com/releases/v1",
+			wantTitle: "Release v1",
+		},
+		{
+			name:   "explicit URL without title uses URL as title",
+			facets: nil,
+			opts: LinkCardOptions{
+				URL:   "https://example.com/custom",
+				Index: 1,
+			},
+			wantURI:   "https://example.com/custom",
+			wantTitle: "https://example.com/custom",
+		},
+		{
+			name:   "explicit card with thumbnail",
+			facets: facets,
+			opts: LinkCardOptions{
+				Title:         "With thumbnail",
+				ThumbnailPath: thumbPath,
+				Index:         1,
+			},
+			wantURI:   "https://docs.example.com",
+			wantTitle: "With thumbnail",
+			wantThumb: true,
+		},
+		{
+			name:   "explicit card ignores disabled embeds",
+			facets: facets,
+			opts: LinkCardOptions{
+				Title: "Forced card",
+				Index: 1,
+			},
+			enableEmbeds: false,
+			wantURI:      "https://docs.example.com",
+			wantTitle:    "Forced card",
+		},
+		{
+			name:         "embeds disabled without overrides",
+			facets:       facets,
+			opts:         LinkCardOptions{Index: 1},
+			enableEmbeds: false,
+			wantNil:      true,
+		},
+		{
+			name:    "overrides without any URL",
+			facets:  nil,
+			opts:    LinkCardOptions{Title: "No URL", Index: 1},
+			wantNil: true,
+		},
+		{
+			name:   "explicit URL ignores invalid selection options",
+			facets: facets,
+			opts: LinkCardOptions{
+				URL:       "https://example.com/custom",
+				Selection: "match",
+				Pattern:   "(",
+				Index:     1,
+			},
+			wantURI:   "https://example.com/custom",
+			wantTitle: "https://example.com/custom",
+		},
+		{
+			name:   "explicit URL ignores match policy without filters",
+			facets: facets,
+			opts: LinkCardOptions{
+				URL:       "https://example.com/custom",
+				Selection: "match",
+				Index:     1,
+			},
+			wantURI:   "https://example.com/custom",
+			wantTitle: "https://example.com/custom",
+		},
+		{
+			name:    "index out of range",
+			facets:  facets,
+			opts:    LinkCardOptions{Title: "Out of range", Selection: "index", Index: 5},
+			wantErr: true,
+		},
+		{
+			name:   "missing thumbnail file",
+			facets: facets,
+			opts: LinkCardOptions{
+				ThumbnailPath: filepath.Join(tempDir, "missing.png"),
+				Index:         1,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			card, err := processLinkCard(mockServer.URL, "fake-token", tc.facets, tc.opts, tc.enableEmbeds, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("processLinkCard() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if tc.wantNil {
+				if card != nil {
+					t.Errorf("processLinkCard() = %+v, want nil", card)
+				}
+				return
+			}
+
+			if card == nil {
+				t.Fatal("processLinkCard() returned nil card")
+			}
+			if card.Type != "app.bsky.embed.external" {
+				t.Errorf("processLinkCard() type = %s, want app.bsky.embed.external", card.Type)
+			}
+			if card.External.URI != tc.wantURI {
+				t.Errorf("processLinkCard() uri = %s, want %s", card.External.URI, tc.wantURI)
+			}
+			if card.External.Title != tc.wantTitle {
+				t.Errorf("processLinkCard() title = %s, want %s", card.External.Title, tc.wantTitle)
+			}
+			if (card.External.Thumb != nil) != tc.wantThumb {
+				t.Errorf("processLinkCard() thumb = %v, wantThumb %v", card.External.Thumb, tc.wantThumb)
+			}
+		})
+	}
+}
+
+func TestProcessLinkCardFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	brokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer brokenServer.Close()
+
+	pageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Release notes</title></head></html>`))
+	}))
+	defer pageServer.Close()
+
+	t.Run("skips candidates without metadata", func(t *testing.T) {
+		facets := parseRichTextFacets("Broken " + brokenServer.URL + "/docs and working " + pageServer.URL + "/release")
+
+		card, err := processLinkCard("", "", facets, LinkCardOptions{Selection: "first"}, true, logger)
+		if err != nil {
+			t.Fatalf("processLinkCard() unexpected error = %v", err)
+		}
+		if card == nil {
+			t.Fatal("processLinkCard() returned nil card, want fallback to second URL")
+		}
+		if card.External.URI != pageServer.URL+"/release" {
+			t.Errorf("processLinkCard() uri = %s, want %s", card.External.URI, pageServer.URL+"/release")
+		}
+		if card.External.Title != "Release notes" {
+			t.Errorf("processLinkCard() title = %s, want 'Release notes'", card.External.Title)
+		}
+	})
+
+	t.Run("no card when all candidates fail", func(t *testing.T) {
+		facets := parseRichTextFacets("Broken " + brokenServer.URL + "/one and " + brokenServer.URL + "/two")
+
+		card, err := processLinkCard("", "", facets, LinkCardOptions{Selection: "last"}, true, logger)
+		if err != nil {
+			t.Fatalf("processLinkCard() unexpected error = %v", err)
+		}
+		if card != nil {
+			t.Errorf("processLinkCard() = %+v, want nil", card)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/alexflint/go-arg"
@@ -27,6 +29,7 @@ var (
 type SessionResponse struct {
 	AccessToken string `json:"accessJwt"` // JWT access token.
 	UserID      string `json:"did"`       // User identifier.
+	Handle      string `json:"handle"`    // User handle.
 }
 
 // Post represents a message to be published to the server.
@@ -37,6 +40,19 @@ type Post struct {
 	Langs     []string        `json:"langs,omitempty"`  // Optional languages the post supports.
 	Facets    []RichTextFacet `json:"facets,omitempty"` // Rich text facets for links, mentions, hashtags.
 	Embed     interface{}     `json:"embed,omitempty"`  // Embed can be EmbedExternal or EmbedImages.
+	Reply     *ReplyRef       `json:"reply,omitempty"`  // Optional parent and root of a reply.
+}
+
+// StrongRef identifies a specific version of a record by URI and content hash.
+type StrongRef struct {
+	URI string `json:"uri"` // AT-URI of the record.
+	CID string `json:"cid"` // CID of the record version.
+}
+
+// ReplyRef links a reply post to its parent and the root of the thread.
+type ReplyRef struct {
+	Root   StrongRef `json:"root"`   // First post of the thread.
+	Parent StrongRef `json:"parent"` // Post being replied to.
 }
 
 // ActionInputs aggregates command line arguments and environment variables for application configuration.
@@ -44,7 +60,10 @@ type ActionInputs struct {
 	PDSURL        string   `arg:"--pds-url" env:"ATP_PDS_HOST" default:"https://bsky.social"` // Base URL of the PDS service.
 	Handle        string   `arg:"--handle,required" env:"ATP_AUTH_HANDLE"`                    // User handle for authentication.
 	Password      string   `arg:"--password,required" env:"ATP_AUTH_PASSWORD"`                // Password for authentication.
-	Text          string   `arg:"--text,required" env:"BSKY_MESSAGE"`                         // Text content for the new post.
+	Text          string   `arg:"--text" env:"BSKY_MESSAGE"`                                  // Text content for the new post.
+	DeleteURI     string   `arg:"--delete-uri" env:"BSKY_DELETE_URI"`                         // AT-URI of a post to delete instead of posting.
+	TextFile      string   `arg:"--text-file" env:"BSKY_TEXT_FILE"`                           // File with the post text, or "-" for stdin.
+	TextTemplate  string   `arg:"--text-template" env:"BSKY_TEXT_TEMPLATE"`                   // Go template rendered into the post text.
 	Lang          []string `arg:"--lang" env:"BSKY_LANG"`                                     // Languages for the new post.
 	LogLevel      string   `arg:"--log-level" env:"LOG_LEVEL" default:"info"`                 // Logging level.
 	EnableEmbeds  bool     `arg:"--enable-embeds" env:"BSKY_ENABLE_EMBEDS" default:"true"`    // Enable link card embeds.
@@ -52,6 +71,44 @@ type ActionInputs struct {
 	ImageAltTexts string   `arg:"--image-alt-texts" env:"BSKY_IMAGE_ALT_TEXTS"`               // Comma-separated alt texts for images.
 	VideoPath     string   `arg:"--video-path" env:"BSKY_VIDEO_PATH"`                         // Video file path.
 	VideoAltText  string   `arg:"--video-alt-text" env:"BSKY_VIDEO_ALT_TEXT"`                 // Alt text for video.
+
+	VideoCaptions          string `arg:"--video-captions" env:"BSKY_VIDEO_CAPTIONS"`                                   // Comma-separated "lang=path" WebVTT caption files.
+	VideoMaxDuration       int    `arg:"--video-max-duration" env:"BSKY_VIDEO_MAX_DURATION" default:"0"`               // Longest accepted video in seconds; zero uses the service maximum.
+	VideoProcessingTimeout int    `arg:"--video-processing-timeout" env:"BSKY_VIDEO_PROCESSING_TIMEOUT" default:"300"` // Seconds to wait for video processing.
+	VideoFallback          string `arg:"--video-fallback" env:"BSKY_VIDEO_FALLBACK" default:"none"`                    // Handling of an unavailable video service: none, blob, or drop.
+
+	AltTextPolicy      string `arg:"--alt-text-policy" env:"BSKY_ALT_TEXT_POLICY" default:"default"` // Handling of missing alt text: require, warn, or default.
+	AltTextCheckLength bool   `arg:"--alt-text-check-length" env:"BSKY_ALT_TEXT_CHECK_LENGTH"`       // Validate alt texts against the 2000 grapheme limit.
+
+	GitHubToken string `arg:"--github-token" env:"GITHUB_TOKEN"` // Token for downloading remote media and releases from GitHub.
+
+	ReleaseNotes        bool   `arg:"--release-notes" env:"BSKY_RELEASE_NOTES"`                             // Summarize a GitHub release as the post text.
+	ReleaseTag          string `arg:"--release-tag" env:"BSKY_RELEASE_TAG"`                                 // Tag of the release to summarize.
+	ReleaseNotesBullets int    `arg:"--release-notes-bullets" env:"BSKY_RELEASE_NOTES_BULLETS" default:"3"` // Maximum number of bullet points in the summary.
+
+	IdempotencyKey  string `arg:"--idempotency-key" env:"BSKY_IDEMPOTENCY_KEY"`                  // Key from which the record key of the post is derived.
+	IdempotencyMode string `arg:"--idempotency-mode" env:"BSKY_IDEMPOTENCY_MODE" default:"skip"` // Handling of an existing post: skip or update.
+
+	ImageAutoResize    bool   `arg:"--image-auto-resize" env:"BSKY_IMAGE_AUTO_RESIZE"`                      // Downscale and re-encode oversized images.
+	ImageMaxDimension  int    `arg:"--image-max-dimension" env:"BSKY_IMAGE_MAX_DIMENSION" default:"2000"`   // Maximum width or height of resized images.
+	ImageStrictType    bool   `arg:"--image-strict-type" env:"BSKY_IMAGE_STRICT_TYPE"`                      // Reject images whose content does not match their extension.
+	ImageStripMetadata bool   `arg:"--image-strip-metadata" env:"BSKY_IMAGE_STRIP_METADATA" default:"true"` // Remove EXIF, XMP, IPTC, and text metadata from images.
+	ImageOverflow      string `arg:"--image-overflow" env:"BSKY_IMAGE_OVERFLOW" default:"error"`            // Handling of more than four images: error or reply.
+	ImageAltSidecars   bool   `arg:"--image-alt-sidecars" env:"BSKY_IMAGE_ALT_SIDECARS"`                    // Read alt texts from "<image>.alt.txt" files.
+	ImageGIFToVideo    bool   `arg:"--image-gif-to-video" env:"BSKY_IMAGE_GIF_TO_VIDEO"`                    // Post a single animated GIF as a looping video.
+	MediaManifest      string `arg:"--media-manifest" env:"BSKY_MEDIA_MANIFEST"`                            // YAML or JSON file listing images with alt texts.
+
+	LinkCardURL         string `arg:"--link-card-url" env:"BSKY_LINK_CARD_URL"`                             // Explicit link card URL.
+	LinkCardTitle       string `arg:"--link-card-title" env:"BSKY_LINK_CARD_TITLE"`                         // Explicit link card title.
+	LinkCardDescription string `arg:"--link-card-description" env:"BSKY_LINK_CARD_DESCRIPTION"`             // Explicit link card description.
+	LinkCardThumbnail   string `arg:"--link-card-thumbnail" env:"BSKY_LINK_CARD_THUMBNAIL"`                 // Local thumbnail image for the link card.
+	LinkCardSelection   string `arg:"--link-card-selection" env:"BSKY_LINK_CARD_SELECTION" default:"first"` // Link card URL selection policy.
+	LinkCardDomains     string `arg:"--link-card-domains" env:"BSKY_LINK_CARD_DOMAINS"`                     // Comma-separated domain allow-list for link card selection.
+	LinkCardPattern     string `arg:"--link-card-pattern" env:"BSKY_LINK_CARD_PATTERN"`                     // Regex for link card selection.
+	LinkCardIndex       int    `arg:"--link-card-index" env:"BSKY_LINK_CARD_INDEX" default:"1"`             // 1-based position of the URL used for the link card.
+	LinkCardKeepURL     bool   `arg:"--link-card-keep-url" env:"BSKY_LINK_CARD_KEEP_URL"`                   // Append the URL of a link card ignored for media to the text.
+
+	EmbedStrict bool `arg:"--embed-strict" env:"BSKY_EMBED_STRICT"` // Fail instead of ignoring inputs overridden by the embed priority.
 }
 
 // createSession initiates a new session with the PDS service.
@@ -84,24 +141,31 @@ func createSession(pdsURL, handle, password string) (*SessionResponse, error) {
 	return &sessionResponse, nil
 }
 
-// publishPost submits a new post to the PDS service using the provided session.
-// nolint: errcheck
-func publishPost(pdsURL string, session *SessionResponse, post *Post, logger *slog.Logger) error {
-	postURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.createRecord", pdsURL)
-	postData, err := json.Marshal(map[string]interface{}{
+// publishPost submits a new post to the PDS service using the provided session
+// and returns a reference to the created record.
+func publishPost(pdsURL string, session *SessionResponse, post *Post, logger *slog.Logger) (*StrongRef, error) {
+	return writePostRecord(pdsURL, "com.atproto.repo.createRecord", session, map[string]interface{}{
 		"repo":       session.UserID,
 		"collection": "app.bsky.feed.post",
 		"record":     post,
-	})
+	}, logger)
+}
+
+// writePostRecord sends a record write request for a post to the PDS service
+// and returns a reference to the written record.
+// nolint: errcheck
+func writePostRecord(pdsURL, method string, session *SessionResponse, data map[string]interface{}, logger *slog.Logger) (*StrongRef, error) {
+	postURL := fmt.Sprintf("%s/xrpc/%s", pdsURL, method)
+	postData, err := json.Marshal(data)
 	if err != nil {
 		logger.Error("Error marshaling post data", "err", err)
-		return err
+		return nil, err
 	}
 
 	request, err := http.NewRequest("POST", postURL, bytes.NewBuffer(postData))
 	if err != nil {
 		logger.Error("Error creating new request", "err", err)
-		return err
+		return nil, err
 	}
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Authorization", "Bearer "+session.AccessToken)
@@ -110,25 +174,37 @@ func publishPost(pdsURL string, session *SessionResponse, post *Post, logger *sl
 	resp, err := client.Do(request)
 	if err != nil {
 		logger.Error("Error sending request", "err", err)
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		logger.Error("Failed to publish post", "statusCode", resp.StatusCode, "body", string(body))
-		return fmt.Errorf("failed to publish post, status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to publish post, status code: %d, body: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	var record StrongRef
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		logger.Error("Error decoding post response", "err", err)
+		return nil, err
+	}
+
+	return &record, nil
 }
 
 // uploadBlob uploads a blob (image or small file) to the PDS service.
-// nolint: errcheck
 func uploadBlob(pdsURL, accessToken string, data []byte, mimeType string, logger *slog.Logger) (*Blob, error) {
+	return uploadBlobContext(context.Background(), pdsURL, accessToken, data, mimeType, logger)
+}
+
+// uploadBlobContext uploads a blob to the PDS service, aborting the request
+// when the context is canceled.
+// nolint: errcheck
+func uploadBlobContext(ctx context.Context, pdsURL, accessToken string, data []byte, mimeType string, logger *slog.Logger) (*Blob, error) {
 	uploadURL := fmt.Sprintf("%s/xrpc/com.atproto.repo.uploadBlob", pdsURL)
 
-	request, err := http.NewRequest("POST", uploadURL, bytes.NewBuffer(data))
+	request, err := http.NewRequestWithContext(ctx, "POST", uploadURL, bytes.NewBuffer(data))
 	if err != nil {
 		logger.Error("Error creating blob upload request", "err", err)
 		return nil, err
@@ -163,6 +239,145 @@ func uploadBlob(pdsURL, accessToken string, data []byte, mimeType string, logger
 	return &blobResp.Blob, nil
 }
 
+// altTextPolicy returns the alt text policy configured in the inputs.
+func altTextPolicy(args ActionInputs) AltTextPolicy {
+	return AltTextPolicy{
+		Mode:        args.AltTextPolicy,
+		CheckLength: args.AltTextCheckLength,
+	}
+}
+
+// videoOptions returns the video options configured in the inputs.
+func videoOptions(args ActionInputs) VideoOptions {
+	return VideoOptions{
+		AltText:     altTextPolicy(args),
+		GitHubToken: args.GitHubToken,
+		Captions:    args.VideoCaptions,
+		MaxDuration: time.Duration(args.VideoMaxDuration) * time.Second,
+		MaxWait:     time.Duration(args.VideoProcessingTimeout) * time.Second,
+		Fallback:    args.VideoFallback,
+	}
+}
+
+// processVideoEmbed uploads the video given in the inputs. If the video service
+// is unavailable and the fallback policy is drop, it returns no embed and
+// reports the video as dropped.
+func processVideoEmbed(args ActionInputs, session *SessionResponse, logger *slog.Logger) (*EmbedVideo, bool, error) {
+	videoEmbed, err := processVideos(args.PDSURL, session.AccessToken, session.UserID, args.VideoPath, args.VideoAltText, videoOptions(args), logger)
+	if err != nil && args.VideoFallback == videoFallbackDrop && isVideoServiceError(err) {
+		logger.Warn("Video service unavailable, posting without the video", "err", err)
+		return nil, true, nil
+	}
+	return videoEmbed, false, err
+}
+
+// linkCardOptions returns the link card options configured in the inputs,
+// preferring the page of the summarized release, if any.
+func linkCardOptions(args ActionInputs, release *GitHubRelease) LinkCardOptions {
+	opts := LinkCardOptions{
+		URL:           args.LinkCardURL,
+		Title:         args.LinkCardTitle,
+		Description:   args.LinkCardDescription,
+		ThumbnailPath: args.LinkCardThumbnail,
+		Selection:     args.LinkCardSelection,
+		Domains:       args.LinkCardDomains,
+		Pattern:       args.LinkCardPattern,
+		Index:         args.LinkCardIndex,
+		StripMetadata: args.ImageStripMetadata,
+	}
+	if release != nil {
+		opts.PreferredURL = release.URL
+	}
+	return opts
+}
+
+// imageOptions returns the image options configured in the inputs.
+func imageOptions(args ActionInputs) ImageOptions {
+	return ImageOptions{
+		AutoResize:     args.ImageAutoResize,
+		MaxDimension:   args.ImageMaxDimension,
+		StrictMimeType: args.ImageStrictType,
+		StripMetadata:  args.ImageStripMetadata,
+		MediaManifest:  args.MediaManifest,
+		AltSidecars:    args.ImageAltSidecars,
+		AltText:        altTextPolicy(args),
+		GitHubToken:    args.GitHubToken,
+	}
+}
+
+// processImageMedia processes the image inputs into the embed of the post and
+// the image embeds of replies. With GIF conversion enabled, a single animated
+// GIF becomes a looping video embed.
+func processImageMedia(args ActionInputs, session *SessionResponse, logger *slog.Logger) (interface{}, []*EmbedImages, error) {
+	if args.ImageGIFToVideo {
+		videoEmbed, err := processAnimatedGIF(args, session, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		if videoEmbed != nil {
+			logger.Info("Animated GIF processed successfully as video")
+			return videoEmbed, nil, nil
+		}
+	}
+
+	imageEmbeds, err := processImageEmbeds(args, session.AccessToken, logger)
+	if err != nil || len(imageEmbeds) == 0 {
+		return nil, nil, err
+	}
+
+	logger.Info("Images processed successfully", "count", len(imageEmbeds[0].Images), "replies", len(imageEmbeds)-1)
+	return imageEmbeds[0], imageEmbeds[1:], nil
+}
+
+// processImageEmbeds uploads the images given in the inputs. The first embed
+// belongs to the post itself, any further embeds to replies when the overflow
+// policy allows spilling images into replies.
+func processImageEmbeds(args ActionInputs, accessToken string, logger *slog.Logger) ([]*EmbedImages, error) {
+	opts := imageOptions(args)
+
+	switch args.ImageOverflow {
+	case "", imageOverflowError:
+		imageEmbed, err := processImages(args.PDSURL, accessToken, args.ImagePaths, args.ImageAltTexts, opts, logger)
+		if err != nil || imageEmbed == nil {
+			return nil, err
+		}
+		return []*EmbedImages{imageEmbed}, nil
+	case imageOverflowReply:
+		return processImageGroups(args.PDSURL, accessToken, args.ImagePaths, args.ImageAltTexts, opts, logger)
+	default:
+		return nil, fmt.Errorf("unknown image overflow policy %q (supported: error, reply)", args.ImageOverflow)
+	}
+}
+
+// publishImageReplies publishes a chain of replies below the root post, one
+// per image embed, each replying to the previous one.
+func publishImageReplies(pdsURL string, session *SessionResponse, root *StrongRef, imageEmbeds []*EmbedImages, langs []string, logger *slog.Logger) error {
+	parent := root
+	for i, imageEmbed := range imageEmbeds {
+		reply := &Post{
+			Type:      "app.bsky.feed.post",
+			Text:      "",
+			CreatedAt: time.Now().Format(time.RFC3339),
+			Langs:     langs,
+			Embed:     imageEmbed,
+			Reply: &ReplyRef{
+				Root:   *root,
+				Parent: *parent,
+			},
+		}
+
+		record, err := publishPost(pdsURL, session, reply, logger)
+		if err != nil {
+			return fmt.Errorf("failed to publish image reply %d: %w", i+1, err)
+		}
+
+		logger.Info("Image reply published successfully", "reply", i+1, "count", len(imageEmbed.Images))
+		parent = record
+	}
+
+	return nil
+}
+
 // stringToLogLevel converts a string representation of a log level to its slog.Level counterpart.
 func stringToLogLevel(level string) slog.Level {
 	switch level {
@@ -179,6 +394,46 @@ func stringToLogLevel(level string) slog.Level {
 	}
 }
 
+// processEmbed builds the embed of the post, chosen by the priority video >
+// images > link card, and the image embeds of replies. The link card prefers
+// the page of the summarized release, if any.
+func processEmbed(args ActionInputs, session *SessionResponse, facets []RichTextFacet, release *GitHubRelease, logger *slog.Logger) (interface{}, []*EmbedImages, error) {
+	// Process video if provided (takes priority)
+	if args.VideoPath != "" {
+		logger.Info("Processing video for upload")
+		videoEmbed, dropped, err := processVideoEmbed(args, session, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to process video: %w", err)
+		}
+		setOutputs(map[string]string{"video-dropped": strconv.FormatBool(dropped)}, logger)
+		if videoEmbed == nil {
+			return nil, nil, nil
+		}
+		logger.Info("Video processed successfully")
+		return videoEmbed, nil, nil
+	}
+
+	// Process images if no video provided
+	if args.ImagePaths != "" || args.MediaManifest != "" {
+		logger.Info("Processing images for upload")
+		embed, replyImages, err := processImageMedia(args, session, logger)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to process images: %w", err)
+		}
+		return embed, replyImages, nil
+	}
+
+	// Create a link card if no media provided
+	linkCard, err := processLinkCard(args.PDSURL, session.AccessToken, facets, linkCardOptions(args, release), args.EnableEmbeds, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to process link card: %w", err)
+	}
+	if linkCard == nil {
+		return nil, nil, nil
+	}
+	return linkCard, nil, nil
+}
+
 // setupLogger configures and returns a new logger based on the provided log level.
 func setupLogger(level string) *slog.Logger {
 	logLevel := stringToLogLevel(level)
@@ -194,6 +449,27 @@ func main() {
 
 	logger := setupLogger(args.LogLevel)
 
+	if args.DeleteURI != "" {
+		if err := runDelete(args, logger); err != nil {
+			logger.Error("Error deleting post", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	release, err := releaseForPost(args, logger)
+	if err != nil {
+		logger.Error("Error loading release", "err", err)
+		os.Exit(1)
+	}
+
+	text, err := postText(args, release, os.Stdin)
+	if err != nil {
+		logger.Error("Error preparing post text", "err", err)
+		os.Exit(1)
+	}
+	args.Text = text
+
 	logger.Info("Starting session creation")
 	session, err := createSession(args.PDSURL, args.Handle, args.Password)
 	if err != nil {
@@ -203,37 +479,37 @@ func main() {
 
 	logger.Debug("Session created successfully", "userID", session.UserID)
 
+	// Look up a post published by an earlier run before uploading anything
+	rkey, existing, err := findExistingPost(args, session, logger)
+	if err != nil {
+		logger.Error("Error checking for an existing post", "err", err)
+		os.Exit(1)
+	}
+	if existing != nil && args.IdempotencyMode == idempotencyModeSkip {
+		logger.Info("Post already published, skipping", "uri", existing.URI)
+		setOutputs(map[string]string{
+			"post-uri": existing.URI,
+			"post-cid": existing.CID,
+			"skipped":  "true",
+			"success":  "true",
+		}, logger)
+		return
+	}
+
+	// Report inputs ignored by the embed priority before uploading anything
+	args.Text, err = applyEmbedPriority(args, parseRichTextFacets(args.Text), release, logger)
+	if err != nil {
+		logger.Error("Error combining embeds", "err", err)
+		os.Exit(1)
+	}
+
 	// Parse rich text facets from the text
 	facets := parseRichTextFacets(args.Text)
 
-	// Determine which embed to use (priority: video > images > link cards)
-	var embed interface{}
-
-	// Process video if provided (takes priority)
-	if args.VideoPath != "" {
-		logger.Info("Processing video for upload")
-		videoEmbed, err := processVideos(args.PDSURL, session.AccessToken, session.UserID, args.VideoPath, args.VideoAltText, logger)
-		if err != nil {
-			logger.Error("Error processing video", "err", err)
-			os.Exit(1)
-		}
-		embed = videoEmbed
-		logger.Info("Video processed successfully")
-	} else if args.ImagePaths != "" {
-		// Process images if no video provided
-		logger.Info("Processing images for upload")
-		imageEmbed, err := processImages(args.PDSURL, session.AccessToken, args.ImagePaths, args.ImageAltTexts, logger)
-		if err != nil {
-			logger.Error("Error processing images", "err", err)
-			os.Exit(1)
-		}
-		embed = imageEmbed
-		logger.Info("Images processed successfully", "count", len(imageEmbed.Images))
-	} else if args.EnableEmbeds && len(facets) > 0 {
-		// Create embed for the first URL if embeds are enabled and no media provided
-		firstURL := facets[0].Features[0].URI
-		logger.Debug("Fetching embed metadata", "url", firstURL)
-		embed = fetchLinkMetadata(firstURL, logger)
+	embed, replyImages, err := processEmbed(args, session, facets, release, logger)
+	if err != nil {
+		logger.Error("Error processing embed", "err", err)
+		os.Exit(1)
 	}
 
 	post := &Post{
@@ -245,10 +521,27 @@ func main() {
 		Embed:     embed,
 	}
 
-	if err := publishPost(args.PDSURL, session, post, logger); err != nil {
+	record, err := publishKeyedPost(args.PDSURL, session, post, rkey, existing, logger)
+	if err != nil {
 		logger.Error("Error publishing post", "err", err)
 		os.Exit(1)
 	}
 
-	logger.Info("Post published successfully")
+	logger.Info("Post published successfully", "uri", record.URI)
+	setOutputs(map[string]string{
+		"post-uri": record.URI,
+		"post-cid": record.CID,
+		"skipped":  "false",
+	}, logger)
+
+	if existing != nil && len(replyImages) > 0 {
+		// The replies of the existing post are kept rather than posted again.
+		logger.Warn("Image replies are not updated with the existing post", "replies", len(replyImages))
+		replyImages = nil
+	}
+	if err := publishImageReplies(args.PDSURL, session, record, replyImages, args.Lang, logger); err != nil {
+		logger.Error("Error publishing image replies", "err", err)
+		os.Exit(1)
+	}
+	setOutputs(map[string]string{"success": "true"}, logger)
 }
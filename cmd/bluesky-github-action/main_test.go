@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestCreateSession(t *testing.T) {
@@ -113,7 +117,7 @@ func TestPublishPost(t *testing.T) {
 			}))
 			defer mockServer.Close()
 
-			err := publishPost(mockServer.URL, tc.session, tc.post, logger)
+			_, err := publishPost(mockServer.URL, tc.session, tc.post, logger)
 
 			if (err != nil) != tc.wantErr {
 				t.Errorf("publishPost() error = %v, wantErr %v", err, tc.wantErr)
@@ -206,3 +210,166 @@ func TestUploadBlob(t *testing.T) {
 	}
 }
 
+func TestPublishImageReplies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var records []map[string]interface{}
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		records = append(records, body["record"].(map[string]interface{}))
+
+		n := len(records)
+		fmt.Fprintf(w, `{"uri": "at://did:plc:test/app.bsky.feed.post/reply%d", "cid": "cid%d"}`, n, n)
+	}))
+	defer mockServer.Close()
+
+	session := &SessionResponse{AccessToken: "fake-jwt-token", UserID: "did:plc:test"}
+	root := &StrongRef{URI: "at://did:plc:test/app.bsky.feed.post/root", CID: "rootcid"}
+	imageEmbeds := []*EmbedImages{
+		{Type: "app.bsky.embed.images", Images: []EmbedImage{{Alt: "Five"}}},
+		{Type: "app.bsky.embed.images", Images: []EmbedImage{{Alt: "Nine"}}},
+	}
+
+	if err := publishImageReplies(mockServer.URL, session, root, imageEmbeds, []string{"en"}, logger); err != nil {
+		t.Fatalf("publishImageReplies() unexpected error = %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("publishImageReplies() published %d records, want 2", len(records))
+	}
+
+	wantParents := []string{root.URI, "at://did:plc:test/app.bsky.feed.post/reply1"}
+	for i, record := range records {
+		reply := record["reply"].(map[string]interface{})
+		gotRoot := reply["root"].(map[string]interface{})["uri"]
+		gotParent := reply["parent"].(map[string]interface{})["uri"]
+		if gotRoot != root.URI {
+			t.Errorf("reply %d root = %v, want %s", i+1, gotRoot, root.URI)
+		}
+		if gotParent != wantParents[i] {
+			t.Errorf("reply %d parent = %v, want %s", i+1, gotParent, wantParents[i])
+		}
+		if record["embed"] == nil {
+			t.Errorf("reply %d has no embed", i+1)
+		}
+	}
+}
+
+func TestProcessVideoEmbedDrop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir := t.TempDir()
+	videoPath := filepath.Join(tempDir, "demo.mp4")
+	if err := os.WriteFile(videoPath, testVideo(30*time.Second), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+	invalidPath := filepath.Join(tempDir, "corrupt.mp4")
+	if err := os.WriteFile(invalidPath, make([]byte, 1024), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+
+	// The PDS refuses service auth, so the video service cannot be used.
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer pdsServer.Close()
+
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:test"}
+
+	tests := []struct {
+		name        string
+		videoPath   string
+		fallback    string
+		wantDropped bool
+		wantErr     bool
+	}{
+		{name: "drop on service failure", videoPath: videoPath, fallback: videoFallbackDrop, wantDropped: true},
+		{name: "fail without fallback", videoPath: videoPath, fallback: videoFallbackNone, wantErr: true},
+		{name: "invalid video is never dropped", videoPath: invalidPath, fallback: videoFallbackDrop, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := ActionInputs{PDSURL: pdsServer.URL, VideoPath: tc.videoPath, VideoFallback: tc.fallback}
+
+			videoEmbed, dropped, err := processVideoEmbed(args, session, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("processVideoEmbed() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if dropped != tc.wantDropped || (tc.wantDropped && videoEmbed != nil) {
+				t.Errorf("processVideoEmbed() = %v, dropped %v, want dropped %v", videoEmbed, dropped, tc.wantDropped)
+			}
+		})
+	}
+}
+
+func TestProcessEmbedReleaseLinkCard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Page ` + r.URL.Path + `</title><meta property="og:description" content="Highlights"></head></html>`))
+	}))
+	defer pageServer.Close()
+
+	session := &SessionResponse{AccessToken: "access-token", UserID: "did:plc:test"}
+	facets := parseRichTextFacets("Docs " + pageServer.URL + "/docs")
+
+	tests := []struct {
+		name         string
+		release      *GitHubRelease
+		enableEmbeds bool
+		wantURI      string
+		wantTitle    string
+	}{
+		{
+			name:         "release page preferred over text URLs",
+			release:      &GitHubRelease{TagName: "v1.2.0", URL: pageServer.URL + "/releases/tag/v1.2.0"},
+			enableEmbeds: true,
+			wantURI:      pageServer.URL + "/releases/tag/v1.2.0",
+			wantTitle:    "Page /releases/tag/v1.2.0",
+		},
+		{
+			name:         "unavailable release page falls back to text URLs",
+			release:      &GitHubRelease{TagName: "v1.2.0", URL: pageServer.URL + "/missing"},
+			enableEmbeds: true,
+			wantURI:      pageServer.URL + "/docs",
+			wantTitle:    "Page /docs",
+		},
+		{
+			name:         "no card with embeds disabled",
+			release:      &GitHubRelease{TagName: "v1.2.0", URL: pageServer.URL + "/releases/tag/v1.2.0"},
+			enableEmbeds: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := ActionInputs{EnableEmbeds: tc.enableEmbeds, LinkCardSelection: "first", LinkCardIndex: 1}
+
+			embed, _, err := processEmbed(args, session, facets, tc.release, logger)
+			if err != nil {
+				t.Fatalf("processEmbed() unexpected error = %v", err)
+			}
+			if tc.wantURI == "" {
+				if embed != nil {
+					t.Errorf("processEmbed() = %+v, want nil", embed)
+				}
+				return
+			}
+
+			card, ok := embed.(*EmbedExternal)
+			if !ok {
+				t.Fatalf("processEmbed() = %T, want *EmbedExternal", embed)
+			}
+			if card.External.URI != tc.wantURI || card.External.Title != tc.wantTitle || card.External.Description != "Highlights" {
+				t.Errorf("processEmbed() card = %+v, want %s titled %q", card.External, tc.wantURI, tc.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MediaManifest describes the images to attach to a post, read from a YAML or JSON file.
+type MediaManifest struct {
+	Images []MediaManifestImage `json:"images" yaml:"images"`
+}
+
+// MediaManifestImage describes a single image entry of a media manifest.
+type MediaManifestImage struct {
+	Path        string       `json:"path" yaml:"path"`                                   // Image file path, relative to the manifest.
+	Alt         string       `json:"alt" yaml:"alt"`                                     // Alt text for the image.
+	AspectRatio *AspectRatio `json:"aspectRatio,omitempty" yaml:"aspectRatio,omitempty"` // Optional aspect ratio override.
+	Order       int          `json:"order,omitempty" yaml:"order,omitempty"`             // Optional position; lower values come first.
+}
+
+// parseMediaManifest decodes a media manifest as JSON for .json files and as YAML otherwise.
+func parseMediaManifest(path string, data []byte) (*MediaManifest, error) {
+	var manifest MediaManifest
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse media manifest %s: %w", path, err)
+		}
+		return &manifest, nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse media manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+// loadMediaManifest reads a media manifest and returns its images sorted by order.
+// Relative image paths are resolved against the directory of the manifest,
+// image URLs are kept as they are.
+// Entries without alt text and sidecar are returned with an empty alt text.
+func loadMediaManifest(path string, useSidecars bool) ([]ImageItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media manifest %s: %w", path, err)
+	}
+
+	manifest, err := parseMediaManifest(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := manifest.Images
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Order < entries[j].Order
+	})
+
+	baseDir := filepath.Dir(path)
+	var items []ImageItem
+
+	for i, entry := range entries {
+		imagePath := strings.TrimSpace(entry.Path)
+		if imagePath == "" {
+			return nil, fmt.Errorf("media manifest %s: image %d has no path", path, i+1)
+		}
+		if !filepath.IsAbs(imagePath) && !isRemoteMedia(imagePath) {
+			imagePath = filepath.Join(baseDir, imagePath)
+		}
+
+		if entry.AspectRatio != nil && (entry.AspectRatio.Width <= 0 || entry.AspectRatio.Height <= 0) {
+			return nil, fmt.Errorf("media manifest %s: image %s has invalid aspect ratio %dx%d",
+				path, entry.Path, entry.AspectRatio.Width, entry.AspectRatio.Height)
+		}
+
+		alt := strings.TrimSpace(entry.Alt)
+		if alt == "" && useSidecars && !isRemoteMedia(imagePath) {
+			alt = readAltSidecar(imagePath)
+		}
+
+		items = append(items, ImageItem{
+			Path:        imagePath,
+			Alt:         alt,
+			AspectRatio: entry.AspectRatio,
+		})
+	}
+
+	return items, nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMediaManifest(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.WriteFile(filepath.Join(tempDir, "b.png.alt.txt"), []byte("Sidecar alt, with a comma\n"), 0644); err != nil {
+		t.Fatalf("Failed to write sidecar: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		filename    string
+		content     string
+		useSidecars bool
+		want        []ImageItem
+		wantErr     bool
+	}{
+		{
+			name:     "YAML sorted by order",
+			filename: "media.yml",
+			content: `images:
+  - path: b.png
+    alt: "Settings panel, dark mode"
+    order: 2
+  - path: a.png
+    alt: "Dashboard, with graphs"
+    order: 1
+    aspectRatio:
+      width: 16
+      height: 9
+`,
+			want: []ImageItem{
+				{Path: filepath.Join(tempDir, "a.png"), Alt: "Dashboard, with graphs", AspectRatio: &AspectRatio{Width: 16, Height: 9}},
+				{Path: filepath.Join(tempDir, "b.png"), Alt: "Settings panel, dark mode"},
+			},
+		},
+		{
+			name:     "JSON with absolute path",
+			filename: "media.json",
+			content:  `{"images": [{"path": "/abs/c.png", "alt": "Release, v1.0.0"}]}`,
+			want: []ImageItem{
+				{Path: "/abs/c.png", Alt: "Release, v1.0.0"},
+			},
+		},
+		{
+			name:        "missing alt read from sidecar",
+			filename:    "sidecar.yaml",
+			content:     "images:\n  - path: b.png\n",
+			useSidecars: true,
+			want: []ImageItem{
+				{Path: filepath.Join(tempDir, "b.png"), Alt: "Sidecar alt, with a comma"},
+			},
+		},
+		{
+			name:     "missing alt without sidecars left empty",
+			filename: "default.yaml",
+			content:  "images:\n  - path: b.png\n",
+			want: []ImageItem{
+				{Path: filepath.Join(tempDir, "b.png"), Alt: ""},
+			},
+		},
+		{
+			name:     "unknown field",
+			filename: "unknown.yaml",
+			content:  "images:\n  - path: a.png\n    caption: typo\n",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown JSON field",
+			filename: "unknown.json",
+			content:  `{"images": [{"file": "a.png"}]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "missing path",
+			filename: "nopath.yaml",
+			content:  "images:\n  - alt: No path\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid aspect ratio",
+			filename: "ratio.yaml",
+			content:  "images:\n  - path: a.png\n    aspectRatio:\n      width: 0\n      height: 9\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			manifestPath := filepath.Join(tempDir, tc.filename)
+			if err := os.WriteFile(manifestPath, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write manifest: %v", err)
+			}
+
+			got, err := loadMediaManifest(manifestPath, tc.useSidecars)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("loadMediaManifest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("loadMediaManifest() = %+v, want %+v", got, tc.want)
+			}
+			for i := range got {
+				if got[i].Path != tc.want[i].Path || got[i].Alt != tc.want[i].Alt {
+					t.Errorf("loadMediaManifest()[%d] = %s %q, want %s %q", i, got[i].Path, got[i].Alt, tc.want[i].Path, tc.want[i].Alt)
+				}
+				if (got[i].AspectRatio == nil) != (tc.want[i].AspectRatio == nil) ||
+					(got[i].AspectRatio != nil && *got[i].AspectRatio != *tc.want[i].AspectRatio) {
+					t.Errorf("loadMediaManifest()[%d] aspectRatio = %v, want %v", i, got[i].AspectRatio, tc.want[i].AspectRatio)
+				}
+			}
+		})
+	}
+
+	t.Run("missing manifest", func(t *testing.T) {
+		if _, err := loadMediaManifest(filepath.Join(tempDir, "missing.yaml"), false); err == nil {
+			t.Error("loadMediaManifest() expected error for missing manifest, got nil")
+		}
+	})
+}
+
+func TestProcessImagesWithManifest(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, name := range []string{"first.png", "second.png"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), fakeImageData(pngMagic, 64), 0644); err != nil {
+			t.Fatalf("Failed to write test image: %v", err)
+		}
+	}
+
+	manifestPath := filepath.Join(tempDir, "media.yaml")
+	manifest := `images:
+  - path: second.png
+    alt: "Second, after first"
+    order: 2
+    aspectRatio: {width: 4, height: 3}
+  - path: first.png
+    alt: "First, before second"
+    order: 1
+`
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]interface{}{"$link": "bafkreimanifest"},
+				"mimeType": "image/png",
+				"size":     64,
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer mockServer.Close()
+
+	// Paths and alt texts are ignored when a manifest is given.
+	result, err := processImages(mockServer.URL, "fake-token", "ignored.png", "Ignored", ImageOptions{MediaManifest: manifestPath}, logger)
+	if err != nil {
+		t.Fatalf("processImages() unexpected error = %v", err)
+	}
+
+	if len(result.Images) != 2 {
+		t.Fatalf("processImages() returned %d images, want 2", len(result.Images))
+	}
+	if result.Images[0].Alt != "First, before second" || result.Images[1].Alt != "Second, after first" {
+		t.Errorf("processImages() alts = %q, %q, want manifest alts in order", result.Images[0].Alt, result.Images[1].Alt)
+	}
+	if ar := result.Images[1].AspectRatio; ar == nil || ar.Width != 4 || ar.Height != 3 {
+		t.Errorf("processImages() aspectRatio = %v, want 4x3 override", ar)
+	}
+}
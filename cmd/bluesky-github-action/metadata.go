@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+)
+
+// JPEG markers of segments that carry metadata rather than image data.
+const (
+	jpegMarkerAPP0 = 0xE0 // JFIF header.
+	jpegMarkerAPP1 = 0xE1 // EXIF and XMP.
+	jpegMarkerAPPD = 0xED // IPTC (Photoshop).
+	jpegMarkerCOM  = 0xFE // Comment.
+	jpegMarkerSOS  = 0xDA // Start of scan.
+)
+
+// pngMetadataChunks lists PNG chunk types that carry textual or EXIF metadata.
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// unstrippableMetadataTypes lists image types that can carry EXIF metadata,
+// including GPS coordinates, but whose metadata cannot be removed.
+var unstrippableMetadataTypes = map[string]bool{
+	"image/avif": true,
+	"image/heic": true,
+}
+
+// stripImageMetadata removes EXIF, XMP, IPTC, and textual metadata from an
+// image. Formats without metadata support are returned unchanged.
+func stripImageMetadata(imageData []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case "image/jpeg":
+		return stripJPEGMetadata(imageData)
+	case "image/png":
+		return stripPNGMetadata(imageData)
+	case "image/webp":
+		return stripWebPMetadata(imageData)
+	default:
+		return imageData, nil
+	}
+}
+
+// stripUploadMetadata removes metadata from an image before upload and warns
+// when the metadata of its format cannot be removed.
+func stripUploadMetadata(path string, imageData []byte, mimeType string, logger *slog.Logger) ([]byte, error) {
+	if unstrippableMetadataTypes[mimeType] {
+		logger.Warn("Cannot strip metadata from this image format, uploading it unchanged", "path", path, "mimeType", mimeType)
+		return imageData, nil
+	}
+
+	stripped, err := stripImageMetadata(imageData, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip metadata from image %s: %w", path, err)
+	}
+	logger.Debug("Stripped image metadata", "path", path, "originalSize", len(imageData), "size", len(stripped))
+	return stripped, nil
+}
+
+// orientationExifSegment builds a minimal JPEG APP1 segment holding only the
+// EXIF orientation tag, so rotated photos keep displaying upright.
+func orientationExifSegment(orientation int) []byte {
+	// Big-endian TIFF header followed by IFD0 with a single SHORT entry.
+	tiff := []byte{'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, 0x00, 0x01}
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:2], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:4], 3)
+	binary.BigEndian.PutUint32(entry[4:8], 1)
+	binary.BigEndian.PutUint16(entry[8:10], uint16(orientation))
+	tiff = append(tiff, entry...)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, jpegMarkerAPP1, 0x00, 0x00}
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// stripJPEGMetadata drops APP1, APP13, and comment segments from a JPEG image.
+// A non-default EXIF orientation is preserved in a minimal EXIF segment.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil, fmt.Errorf("invalid JPEG header")
+	}
+
+	orientation := jpegOrientation(data)
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientationWritten := orientation <= 1
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", offset)
+		}
+		marker := data[offset+1]
+		// Fill bytes may precede a marker.
+		if marker == 0xFF {
+			offset++
+			continue
+		}
+
+		// The orientation segment goes right after the JFIF header, if any.
+		if !orientationWritten && marker != jpegMarkerAPP0 {
+			out = append(out, orientationExifSegment(orientation)...)
+			orientationWritten = true
+		}
+
+		// Everything from the start of scan on is image data.
+		if marker == jpegMarkerSOS {
+			return append(out, data[offset:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("invalid JPEG segment length at offset %d", offset)
+		}
+
+		switch marker {
+		case jpegMarkerAPP1, jpegMarkerAPPD, jpegMarkerCOM:
+			// Drop metadata segment.
+		default:
+			out = append(out, data[offset:end]...)
+		}
+		offset = end
+	}
+
+	return nil, fmt.Errorf("truncated JPEG image")
+}
+
+// stripPNGMetadata drops text, EXIF, and modification time chunks from a PNG image.
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, fmt.Errorf("invalid PNG header")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+
+	for offset := len(signature); offset < len(data); {
+		if offset+12 > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk at offset %d", offset)
+		}
+		length := uint64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		chunkType := string(data[offset+4 : offset+8])
+		if uint64(offset)+12+length > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid PNG chunk length at offset %d", offset)
+		}
+		end := offset + 12 + int(length)
+
+		if !pngMetadataChunks[chunkType] {
+			out = append(out, data[offset:end]...)
+		}
+		offset = end
+
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out, nil
+}
+
+// stripWebPMetadata drops EXIF and XMP chunks from a WebP image and clears
+// the corresponding flags in the extended header.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("invalid WebP header")
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[0:12]...)
+
+	for offset := 12; offset+8 <= len(data); {
+		chunkType := string(data[offset : offset+4])
+		size := uint64(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		padded := size + size%2 // Chunks are padded to an even size.
+		if uint64(offset)+8+padded > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid WebP chunk length at offset %d", offset)
+		}
+		end := offset + 8 + int(padded)
+
+		switch chunkType {
+		case "EXIF", "XMP ":
+			// Drop metadata chunk.
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[offset:end]...)
+			if size > 0 {
+				// Clear the EXIF (0x08) and XMP (0x04) presence flags.
+				out[start+8] &^= 0x08 | 0x04
+			}
+		default:
+			out = append(out, data[offset:end]...)
+		}
+		offset = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
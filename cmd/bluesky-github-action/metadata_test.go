@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testGPSExif builds little-endian TIFF data with an orientation, an artist,
+// and a GPS IFD holding a latitude reference.
+func testGPSExif(orientation int) []byte {
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00}
+
+	entry := func(tag, typ uint16, count, value uint32) []byte {
+		e := make([]byte, 12)
+		binary.LittleEndian.PutUint16(e[0:2], tag)
+		binary.LittleEndian.PutUint16(e[2:4], typ)
+		binary.LittleEndian.PutUint32(e[4:8], count)
+		binary.LittleEndian.PutUint32(e[8:12], value)
+		return e
+	}
+
+	// IFD0 at offset 8 with three entries, the artist at 50, and the GPS IFD at 60.
+	tiff = append(tiff, 0x03, 0x00)
+	tiff = append(tiff, entry(0x0112, 3, 1, uint32(orientation))...)
+	tiff = append(tiff, entry(0x013B, 2, 9, 50)...)
+	tiff = append(tiff, entry(0x8825, 4, 1, 60)...)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+	tiff = append(tiff, []byte("Jane Doe\x00\x00")...)
+	tiff = append(tiff, 0x01, 0x00)
+	tiff = append(tiff, entry(0x0001, 2, 2, uint32('N'))...)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00)
+
+	return tiff
+}
+
+// jpegSegment builds a JPEG marker segment with the given payload.
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0x00, 0x00}
+	binary.BigEndian.PutUint16(segment[2:4], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// testGPSJPEG encodes a JPEG image carrying EXIF with GPS tags, XMP, IPTC, and a comment.
+func testGPSJPEG(t *testing.T, orientation int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatalf("Failed to encode test JPEG: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	// Keep the encoder's JFIF header first, then add the metadata segments.
+	jfifLength := int(binary.BigEndian.Uint16(encoded[4:6]))
+	jfifEnd := 4 + jfifLength
+
+	result := append([]byte{}, encoded[:jfifEnd]...)
+	result = append(result, jpegSegment(0xE1, append([]byte("Exif\x00\x00"), testGPSExif(orientation)...))...)
+	result = append(result, jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>Jane Doe</x:xmpmeta>"))...)
+	result = append(result, jpegSegment(0xED, []byte("Photoshop 3.0\x008BIM Jane Doe"))...)
+	result = append(result, jpegSegment(0xFE, []byte("Shot by Jane Doe"))...)
+	return append(result, encoded[jfifEnd:]...)
+}
+
+// pngChunk builds a PNG chunk with a valid CRC.
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 4, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+	return append(chunk, crc...)
+}
+
+// testTextPNG encodes a PNG image carrying text and EXIF chunks.
+func testTextPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode test PNG: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	// Insert the metadata chunks right after the IHDR chunk.
+	ihdrEnd := 8 + 12 + 13
+	result := append([]byte{}, encoded[:ihdrEnd]...)
+	result = append(result, pngChunk("tEXt", []byte("Author\x00Jane Doe"))...)
+	result = append(result, pngChunk("iTXt", []byte("Description\x00\x00\x00\x00\x00Taken at home of Jane Doe"))...)
+	result = append(result, pngChunk("eXIf", testGPSExif(1))...)
+	return append(result, encoded[ihdrEnd:]...)
+}
+
+// testMetadataWebP builds an extended WebP file carrying EXIF and XMP chunks.
+func testMetadataWebP() []byte {
+	chunk := func(chunkType string, data []byte) []byte {
+		c := []byte(chunkType)
+		size := make([]byte, 4)
+		binary.LittleEndian.PutUint32(size, uint32(len(data)))
+		c = append(c, size...)
+		c = append(c, data...)
+		if len(data)%2 == 1 {
+			c = append(c, 0x00)
+		}
+		return c
+	}
+
+	vp8x := []byte{0x0C, 0x00, 0x00, 0x00, 0x7F, 0x07, 0x00, 0xAF, 0x04, 0x00}
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", []byte{0x2F, 0x00, 0x00, 0x00, 0x00})...)
+	body = append(body, chunk("EXIF", testGPSExif(1))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta>Jane Doe</x:xmpmeta>"))...)
+
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(body)))
+	return append(append([]byte("RIFF"), size...), body...)
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	t.Run("JPEG with GPS tags keeps orientation", func(t *testing.T) {
+		original := testGPSJPEG(t, 6)
+
+		stripped, err := stripImageMetadata(original, "image/jpeg")
+		if err != nil {
+			t.Fatalf("stripImageMetadata() unexpected error = %v", err)
+		}
+
+		if bytes.Contains(stripped, []byte("Jane Doe")) {
+			t.Error("stripImageMetadata() left personal metadata in JPEG")
+		}
+		if !bytes.Contains(stripped, orientationExifSegment(6)) {
+			t.Error("stripImageMetadata() did not preserve orientation segment")
+		}
+		if len(stripped) >= len(original) {
+			t.Errorf("stripImageMetadata() size = %d, want less than %d", len(stripped), len(original))
+		}
+		if got := jpegOrientation(stripped); got != 6 {
+			t.Errorf("jpegOrientation() after strip = %d, want 6", got)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("stripped JPEG does not decode: %v", err)
+		}
+	})
+
+	t.Run("JPEG without orientation has no EXIF", func(t *testing.T) {
+		stripped, err := stripImageMetadata(testGPSJPEG(t, 1), "image/jpeg")
+		if err != nil {
+			t.Fatalf("stripImageMetadata() unexpected error = %v", err)
+		}
+		if bytes.Contains(stripped, []byte("Exif\x00\x00")) {
+			t.Error("stripImageMetadata() left EXIF segment in JPEG")
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("stripped JPEG does not decode: %v", err)
+		}
+	})
+
+	t.Run("PNG text and EXIF chunks", func(t *testing.T) {
+		stripped, err := stripImageMetadata(testTextPNG(t), "image/png")
+		if err != nil {
+			t.Fatalf("stripImageMetadata() unexpected error = %v", err)
+		}
+		for _, chunkType := range []string{"tEXt", "iTXt", "eXIf", "Jane Doe"} {
+			if bytes.Contains(stripped, []byte(chunkType)) {
+				t.Errorf("stripImageMetadata() left %q in PNG", chunkType)
+			}
+		}
+		if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("stripped PNG does not decode: %v", err)
+		}
+	})
+
+	t.Run("WebP EXIF and XMP chunks", func(t *testing.T) {
+		stripped, err := stripImageMetadata(testMetadataWebP(), "image/webp")
+		if err != nil {
+			t.Fatalf("stripImageMetadata() unexpected error = %v", err)
+		}
+		if bytes.Contains(stripped, []byte("EXIF")) || bytes.Contains(stripped, []byte("Jane Doe")) {
+			t.Error("stripImageMetadata() left metadata chunks in WebP")
+		}
+		if flags := stripped[20]; flags&0x0C != 0 {
+			t.Errorf("stripImageMetadata() VP8X flags = %#x, want EXIF and XMP flags cleared", flags)
+		}
+		if size := int(binary.LittleEndian.Uint32(stripped[4:8])); size != len(stripped)-8 {
+			t.Errorf("stripImageMetadata() RIFF size = %d, want %d", size, len(stripped)-8)
+		}
+		width, height, err := webpDimensions(stripped)
+		if err != nil || width != 1920 || height != 1200 {
+			t.Errorf("webpDimensions() after strip = %dx%d, %v, want 1920x1200", width, height, err)
+		}
+	})
+
+	t.Run("unsupported format is unchanged", func(t *testing.T) {
+		original := fakeImageData(gifMagic, 64)
+		stripped, err := stripImageMetadata(original, "image/gif")
+		if err != nil {
+			t.Fatalf("stripImageMetadata() unexpected error = %v", err)
+		}
+		if !bytes.Equal(stripped, original) {
+			t.Error("stripImageMetadata() modified GIF data")
+		}
+	})
+
+	t.Run("malformed JPEG", func(t *testing.T) {
+		if _, err := stripImageMetadata([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, "image/jpeg"); err == nil {
+			t.Error("stripImageMetadata() expected error for malformed JPEG, got nil")
+		}
+	})
+
+	t.Run("PNG chunk length overflowing int", func(t *testing.T) {
+		data := []byte("\x89PNG\r\n\x1a\n\x7F\xFF\xFF\xF8tEXt\x00\x00\x00\x00")
+		if _, err := stripImageMetadata(data, "image/png"); err == nil {
+			t.Error("stripImageMetadata() expected error for oversized PNG chunk, got nil")
+		}
+	})
+
+	t.Run("WebP chunk size overflowing int", func(t *testing.T) {
+		data := []byte("RIFF\x00\x00\x00\x00WEBPEXIF\xF6\xFF\xFF\x7F\x00\x00\x00\x00")
+		if _, err := stripImageMetadata(data, "image/webp"); err == nil {
+			t.Error("stripImageMetadata() expected error for oversized WebP chunk, got nil")
+		}
+	})
+}
+
+func TestProcessImagesStripMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	imagePath := filepath.Join(tempDir, "photo.jpg")
+	if err := os.WriteFile(imagePath, testGPSJPEG(t, 1), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		strip        bool
+		wantMetadata bool
+	}{
+		{
+			name:         "metadata stripped",
+			strip:        true,
+			wantMetadata: false,
+		},
+		{
+			name:         "metadata kept when opted out",
+			strip:        false,
+			wantMetadata: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var uploaded []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploaded, _ = io.ReadAll(r.Body)
+				response := map[string]interface{}{
+					"blob": map[string]interface{}{
+						"$type":    "blob",
+						"ref":      map[string]interface{}{"$link": "bafkreiphoto"},
+						"mimeType": "image/jpeg",
+						"size":     len(uploaded),
+					},
+				}
+				json.NewEncoder(w).Encode(response)
+			}))
+			defer mockServer.Close()
+
+			_, err := processImages(mockServer.URL, "fake-token", imagePath, "Photo", ImageOptions{StripMetadata: tc.strip}, logger)
+			if err != nil {
+				t.Fatalf("processImages() unexpected error = %v", err)
+			}
+
+			if got := bytes.Contains(uploaded, []byte("Jane Doe")); got != tc.wantMetadata {
+				t.Errorf("uploaded image contains metadata = %v, want %v", got, tc.wantMetadata)
+			}
+		})
+	}
+}
+
+func TestStripUploadMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		mimeType string
+		wantWarn bool
+	}{
+		{name: "JPEG", data: testGPSJPEG(t, 1), mimeType: "image/jpeg"},
+		{name: "AVIF", data: testHEIF("avif", 0, [2]uint32{640, 480}), mimeType: "image/avif", wantWarn: true},
+		{name: "HEIC", data: testHEIF("heic", 0, [2]uint32{640, 480}), mimeType: "image/heic", wantWarn: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+			got, err := stripUploadMetadata("photo", tc.data, tc.mimeType, logger)
+			if err != nil {
+				t.Fatalf("stripUploadMetadata() unexpected error = %v", err)
+			}
+			if bytes.Contains(got, []byte("Jane Doe")) {
+				t.Errorf("stripUploadMetadata() kept metadata")
+			}
+			if warned := bytes.Contains(logs.Bytes(), []byte("level=WARN")); warned != tc.wantWarn {
+				t.Errorf("stripUploadMetadata() warned = %v, want %v, logs: %s", warned, tc.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+func TestUploadLinkCardThumbnailStripMetadata(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	thumbPath := filepath.Join(t.TempDir(), "thumb.jpg")
+	if err := os.WriteFile(thumbPath, testGPSJPEG(t, 1), 0644); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		strip        bool
+		wantMetadata bool
+	}{
+		{name: "metadata stripped", strip: true, wantMetadata: false},
+		{name: "metadata kept when opted out", strip: false, wantMetadata: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var uploaded []byte
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				uploaded, _ = io.ReadAll(r.Body)
+				w.Write([]byte(`{"blob": {"$type": "blob", "ref": {"$link": "bafkreithumb"}, "mimeType": "image/jpeg", "size": 1}}`))
+			}))
+			defer mockServer.Close()
+
+			opts := LinkCardOptions{URL: "https://example.com", ThumbnailPath: thumbPath, StripMetadata: tc.strip}
+			card, err := processLinkCard(mockServer.URL, "fake-token", nil, opts, true, logger)
+			if err != nil {
+				t.Fatalf("processLinkCard() unexpected error = %v", err)
+			}
+			if card == nil || card.External.Thumb == nil {
+				t.Fatalf("processLinkCard() = %+v, want card with thumbnail", card)
+			}
+
+			if got := bytes.Contains(uploaded, []byte("Jane Doe")); got != tc.wantMetadata {
+				t.Errorf("uploaded thumbnail contains metadata = %v, want %v", got, tc.wantMetadata)
+			}
+		})
+	}
+}
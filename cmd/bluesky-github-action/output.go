@@ -0,0 +1,67 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// setOutput sets a step output by appending it to the file named by
+// GITHUB_OUTPUT. It does nothing when the variable is unset, such as when the
+// action runs outside of GitHub Actions.
+func setOutput(name, value string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		return nil
+	}
+
+	entry := fmt.Sprintf("%s=%s\n", name, value)
+	if strings.ContainsAny(value, "\r\n") {
+		// Multiline values are enclosed in a random delimiter that cannot occur in the value.
+		delimiter, err := outputDelimiter()
+		if err != nil {
+			return err
+		}
+		entry = fmt.Sprintf("%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	if _, err := file.WriteString(entry); err != nil {
+		file.Close() // nolint: errcheck
+		return fmt.Errorf("failed to write output %s: %w", name, err)
+	}
+	return file.Close()
+}
+
+// setOutputs sets step outputs in name order. Failures are logged as warnings
+// because the post has already been published or deleted at this point.
+func setOutputs(outputs map[string]string, logger *slog.Logger) {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := setOutput(name, outputs[name]); err != nil {
+			logger.Warn("Failed to set output", "name", name, "err", err)
+		}
+	}
+}
+
+// outputDelimiter returns a random delimiter for a multiline output value.
+func outputDelimiter() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate output delimiter: %w", err)
+	}
+	return "ghadelimiter_" + hex.EncodeToString(buf), nil
+}
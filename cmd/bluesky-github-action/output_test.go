@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestSetOutput(t *testing.T) {
+	t.Run("single and multiline values", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "output")
+		t.Setenv("GITHUB_OUTPUT", outputPath)
+
+		if err := setOutput("video-dropped", "true"); err != nil {
+			t.Fatalf("setOutput() unexpected error = %v", err)
+		}
+		if err := setOutput("notes", "line one\nline two"); err != nil {
+			t.Fatalf("setOutput() unexpected error = %v", err)
+		}
+
+		content, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+
+		pattern := regexp.MustCompile(`^video-dropped=true\nnotes<<(ghadelimiter_[0-9a-f]{32})\nline one\nline two\n(ghadelimiter_[0-9a-f]{32})\n$`)
+		match := pattern.FindStringSubmatch(string(content))
+		if match == nil || match[1] != match[2] {
+			t.Errorf("output file content = %q", content)
+		}
+	})
+
+	t.Run("outside of GitHub Actions", func(t *testing.T) {
+		t.Setenv("GITHUB_OUTPUT", "")
+		if err := setOutput("video-dropped", "true"); err != nil {
+			t.Errorf("setOutput() unexpected error = %v", err)
+		}
+	})
+}
+
+func TestSetOutputs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outputPath := filepath.Join(t.TempDir(), "output")
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	setOutputs(map[string]string{"success": "true", "post-uri": "at://did:plc:test/app.bsky.feed.post/3k", "post-cid": "bafyrei"}, logger)
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if want := "post-cid=bafyrei\npost-uri=at://did:plc:test/app.bsky.feed.post/3k\nsuccess=true\n"; string(content) != want {
+		t.Errorf("output file content = %q, want %q", content, want)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Constants for release notes posts.
+const (
+	releaseFetchTimeout       = 30 * time.Second
+	maxReleaseBulletGraphemes = 100 // Longer bullet points are truncated.
+	releaseBulletPrefix       = "• "
+)
+
+// Patterns for parsing markdown release notes.
+var (
+	markdownHeadingPattern = regexp.MustCompile(`^#{1,6}\s+`)
+	markdownBulletPattern  = regexp.MustCompile(`^ {0,1}(?:[-*+]|\d+[.)])\s+(.+)$`)
+	markdownImagePattern   = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLinkPattern    = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	markdownAutoLink       = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	markdownHTMLTagPattern = regexp.MustCompile(`</?[A-Za-z][^>]*>|<!--.*?-->`)
+	markdownEmphasis       = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*(.+?)\*\*`),
+		regexp.MustCompile(`__(.+?)__`),
+		regexp.MustCompile(`~~(.+?)~~`),
+		regexp.MustCompile("`([^`]+)`"),
+		regexp.MustCompile(`\*([^*\s][^*]*)\*`),
+	}
+	// generatedNotesAuthorPattern matches the author and pull request suffix of
+	// bullet points in release notes generated by GitHub.
+	generatedNotesAuthorPattern = regexp.MustCompile(`\s+by @[\w-]+(?:\[bot\])? in https?://\S+$`)
+	whitespacePattern           = regexp.MustCompile(`\s+`)
+)
+
+// stripMarkdown converts a line of markdown to plain text, keeping the text of
+// links and emphasis.
+func stripMarkdown(line string) string {
+	line = markdownHeadingPattern.ReplaceAllString(strings.TrimSpace(line), "")
+	line = markdownImagePattern.ReplaceAllString(line, "")
+	line = markdownLinkPattern.ReplaceAllString(line, "$1")
+	line = markdownAutoLink.ReplaceAllString(line, "$1")
+	line = markdownHTMLTagPattern.ReplaceAllString(line, "")
+	for _, pattern := range markdownEmphasis {
+		line = pattern.ReplaceAllString(line, "$1")
+	}
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(line, " "))
+}
+
+// parseReleaseNotes extracts the headline and the top-level bullet points of
+// markdown release notes. The headline is the first paragraph line before the
+// first bullet point; headings are section titles and are skipped.
+func parseReleaseNotes(body string) (string, []string) {
+	var headline string
+	var bullets []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if match := markdownBulletPattern.FindStringSubmatch(line); match != nil {
+			item := generatedNotesAuthorPattern.ReplaceAllString(strings.TrimSpace(match[1]), "")
+			if item = stripMarkdown(item); item != "" {
+				bullets = append(bullets, item)
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if headline != "" || len(bullets) > 0 || trimmed == "" || markdownHeadingPattern.MatchString(trimmed) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "**Full Changelog**") || strings.HasPrefix(trimmed, "<!--") {
+			continue
+		}
+		headline = stripMarkdown(trimmed)
+	}
+
+	return headline, bullets
+}
+
+// joinReleasePost joins the leading lines and bullet points of a release post.
+func joinReleasePost(head, bullets []string) string {
+	text := strings.Join(head, "\n")
+	if len(bullets) > 0 {
+		text += "\n\n" + releaseBulletPrefix + strings.Join(bullets, "\n"+releaseBulletPrefix)
+	}
+	return text
+}
+
+// releaseNotesText summarizes a release as post text: the intro text, the
+// release title, the headline, and up to maxBullets bullet points, dropping
+// bullet points until the text fits into the post length limit.
+func releaseNotesText(intro string, release GitHubRelease, maxBullets int) string {
+	headline, bullets := parseReleaseNotes(release.Body)
+
+	var head []string
+	if intro = strings.TrimSpace(intro); intro != "" {
+		head = append(head, intro)
+	}
+	title := strings.TrimSpace(release.Name)
+	if title == "" {
+		title = release.TagName
+	}
+	head = append(head, title)
+	if headline != "" {
+		head = append(head, headline)
+	}
+
+	bullets = bullets[:min(max(maxBullets, 0), len(bullets))]
+	for i, bullet := range bullets {
+		bullets[i] = truncateGraphemes(bullet, maxReleaseBulletGraphemes)
+	}
+
+	for n := len(bullets); n >= 0; n-- {
+		if text := joinReleasePost(head, bullets[:n]); graphemeCount(text) <= maxPostGraphemes {
+			return text
+		}
+	}
+	return truncateGraphemes(joinReleasePost(head, nil), maxPostGraphemes)
+}
+
+// fetchRelease looks up a release of the workflow repository by tag through
+// the GitHub API.
+// nolint: errcheck
+func fetchRelease(ctx context.Context, tag, githubToken string, logger *slog.Logger) (*GitHubRelease, error) {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	if repository == "" {
+		return nil, fmt.Errorf("GITHUB_REPOSITORY is not set, cannot look up release %s", tag)
+	}
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+
+	releaseURL := joinURL(apiURL, "repos", repository, "releases", "tags", url.PathEscape(tag))
+	request, err := http.NewRequestWithContext(ctx, "GET", releaseURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release URL %s: %w", releaseURL, err)
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	if githubToken != "" {
+		request.Header.Set("Authorization", "Bearer "+githubToken)
+	}
+
+	logger.Info("Fetching release", "repository", repository, "tag", tag)
+
+	client := &http.Client{
+		Timeout: releaseFetchTimeout,
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release %s: %w", tag, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("release %s not found in %s", tag, repository)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch release %s, status code: %d", tag, resp.StatusCode)
+	}
+
+	var release GitHubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to decode release %s: %w", tag, err)
+	}
+	return &release, nil
+}
+
+// loadRelease returns the release announced by a post: the release of the
+// event payload, or the release with the given tag, defaulting to the tag
+// that triggered the workflow.
+func loadRelease(ctx context.Context, tag, githubToken string, logger *slog.Logger) (*GitHubRelease, error) {
+	_, event, err := loadGitHubEvent(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return nil, err
+	}
+	if event.Release != nil && (tag == "" || tag == event.Release.TagName) {
+		logger.Debug("Using release from event payload", "tag", event.Release.TagName)
+		return event.Release, nil
+	}
+
+	if tag == "" && strings.HasPrefix(os.Getenv("GITHUB_REF"), "refs/tags/") {
+		tag = os.Getenv("GITHUB_REF_NAME")
+	}
+	if tag == "" {
+		return nil, fmt.Errorf("no release in the event payload, set release-tag to look it up")
+	}
+
+	return fetchRelease(ctx, tag, githubToken, logger)
+}
+
+// releaseForPost returns the release summarized by the post in release notes
+// mode, or nil when the mode is disabled.
+func releaseForPost(args ActionInputs, logger *slog.Logger) (*GitHubRelease, error) {
+	if !args.ReleaseNotes {
+		return nil, nil
+	}
+	return loadRelease(context.Background(), args.ReleaseTag, args.GitHubToken, logger)
+}
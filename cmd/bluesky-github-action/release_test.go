@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testGeneratedReleaseNotes = `<!-- Release notes generated using configuration in .github/release.yml -->
+
+## What's Changed
+### Features
+* Add **video captions** by @octocat in https://github.com/cbrgm/bluesky-github-action/pull/41
+* Support [media manifests](https://example.com/docs/manifest) by @dependabot[bot] in https://github.com/cbrgm/bluesky-github-action/pull/42
+  * Nested detail
+* Retry ` + "`uploadBlob`" + ` on errors
+* Fourth change
+
+**Full Changelog**: https://github.com/cbrgm/bluesky-github-action/compare/v1.1.0...v1.2.0`
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "## Highlights", want: "Highlights"},
+		{input: "See [the docs](https://example.com) and <https://example.com/faq>", want: "See the docs and https://example.com/faq"},
+		{input: "**Bold**, *italic*, ~~gone~~ and `code`", want: "Bold, italic, gone and code"},
+		{input: "![screenshot](shot.png) New <b>dashboard</b>", want: "New dashboard"},
+		{input: "keeps snake_case_names", want: "keeps snake_case_names"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := stripMarkdown(tc.input); got != tc.want {
+				t.Errorf("stripMarkdown(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseReleaseNotes(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantHeadline string
+		wantBullets  []string
+	}{
+		{
+			name:        "generated release notes",
+			body:        testGeneratedReleaseNotes,
+			wantBullets: []string{"Add video captions", "Support media manifests", "Retry uploadBlob on errors", "Fourth change"},
+		},
+		{
+			name:         "headline paragraph",
+			body:         "# v2.0.0\r\n\r\nThe **biggest** release yet.\r\nMore text.\r\n\r\n- One\r\n1. Two\r\n\r\nClosing words.",
+			wantHeadline: "The biggest release yet.",
+			wantBullets:  []string{"One", "Two"},
+		},
+		{
+			name: "empty body",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			headline, bullets := parseReleaseNotes(tc.body)
+			if headline != tc.wantHeadline {
+				t.Errorf("parseReleaseNotes() headline = %q, want %q", headline, tc.wantHeadline)
+			}
+			if !reflect.DeepEqual(bullets, tc.wantBullets) {
+				t.Errorf("parseReleaseNotes() bullets = %q, want %q", bullets, tc.wantBullets)
+			}
+		})
+	}
+}
+
+func TestReleaseNotesText(t *testing.T) {
+	release := GitHubRelease{Name: "v1.2.0", TagName: "v1.2.0", Body: testGeneratedReleaseNotes}
+	long := strings.Repeat("word ", 30)
+
+	tests := []struct {
+		name       string
+		intro      string
+		release    GitHubRelease
+		maxBullets int
+		want       string
+	}{
+		{
+			name:       "first bullet points",
+			intro:      "🚀 New release",
+			release:    release,
+			maxBullets: 2,
+			want:       "🚀 New release\nv1.2.0\n\n• Add video captions\n• Support media manifests",
+		},
+		{
+			name:       "tag without name or notes",
+			release:    GitHubRelease{TagName: "v1.2.1"},
+			maxBullets: 3,
+			want:       "v1.2.1",
+		},
+		{
+			name:       "no bullet points",
+			release:    release,
+			maxBullets: 0,
+			want:       "v1.2.0",
+		},
+		{
+			name:       "bullet points dropped to fit",
+			release:    GitHubRelease{TagName: "v3", Body: "- " + long + "\n- " + long + "\n- " + long},
+			maxBullets: 3,
+			want:       "v3\n\n• " + truncateGraphemes(long, maxReleaseBulletGraphemes) + "\n• " + truncateGraphemes(long, maxReleaseBulletGraphemes),
+		},
+		{
+			name:       "headline truncated",
+			release:    GitHubRelease{TagName: "v4", Body: strings.Repeat("a", 400)},
+			maxBullets: 3,
+			want:       truncateGraphemes("v4\n"+strings.Repeat("a", 400), maxPostGraphemes),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := releaseNotesText(tc.intro, tc.release, tc.maxBullets)
+			if got != tc.want {
+				t.Errorf("releaseNotesText() = %q, want %q", got, tc.want)
+			}
+			if graphemeCount(got) > maxPostGraphemes {
+				t.Errorf("releaseNotesText() length = %d, exceeds %d", graphemeCount(got), maxPostGraphemes)
+			}
+		})
+	}
+}
+
+func TestLoadRelease(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var gotPath, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		if !strings.HasSuffix(r.URL.Path, "/releases/tags/v1.1.0") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(GitHubRelease{Name: "Older release", TagName: "v1.1.0", URL: "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1.1.0"})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		payload  string
+		tag      string
+		ref      string
+		wantName string
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "release from event payload",
+			payload:  testReleasePayload,
+			wantName: "v1.2.0 \"Aurora\"",
+		},
+		{
+			name:     "release by tag",
+			payload:  testReleasePayload,
+			tag:      "v1.1.0",
+			wantName: "Older release",
+			wantPath: "/repos/cbrgm/bluesky-github-action/releases/tags/v1.1.0",
+		},
+		{
+			name:     "tag of tag push",
+			payload:  `{}`,
+			ref:      "refs/tags/v1.1.0",
+			wantName: "Older release",
+			wantPath: "/repos/cbrgm/bluesky-github-action/releases/tags/v1.1.0",
+		},
+		{
+			name:    "unknown tag",
+			payload: `{}`,
+			tag:     "v9.9.9",
+			wantErr: true,
+		},
+		{
+			name:    "no release",
+			payload: `{}`,
+			ref:     "refs/heads/main",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useGitHubContext(t, tc.payload)
+			t.Setenv("GITHUB_API_URL", server.URL)
+			if tc.ref != "" {
+				t.Setenv("GITHUB_REF", tc.ref)
+				t.Setenv("GITHUB_REF_NAME", strings.TrimPrefix(tc.ref, "refs/tags/"))
+			}
+			gotPath, gotAuth = "", ""
+
+			release, err := loadRelease(context.Background(), tc.tag, "gh-token", logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("loadRelease() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if release.Name != tc.wantName {
+				t.Errorf("loadRelease() name = %q, want %q", release.Name, tc.wantName)
+			}
+			if gotPath != tc.wantPath {
+				t.Errorf("API request path = %q, want %q", gotPath, tc.wantPath)
+			}
+			if tc.wantPath != "" && gotAuth != "Bearer gh-token" {
+				t.Errorf("API request Authorization = %q, want bearer token", gotAuth)
+			}
+		})
+	}
+}
+
+func TestPostTextReleaseNotes(t *testing.T) {
+	t.Setenv("GITHUB_EVENT_PATH", "")
+
+	release := &GitHubRelease{Name: "v1.2.0", Body: "- Faster uploads"}
+	got, err := postText(ActionInputs{Text: "New release", ReleaseNotes: true, ReleaseNotesBullets: 3}, release, strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("postText() unexpected error = %v", err)
+	}
+	if want := "New release\nv1.2.0\n\n• Faster uploads"; got != want {
+		t.Errorf("postText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Constants for downloading remote media.
+const (
+	maxRemoteImageSize    = 20 * 1024 * 1024 // Larger than maxImageSize so auto-resize can shrink downloads.
+	remoteDownloadTimeout = 2 * time.Minute
+)
+
+// githubHosts lists the hosts that receive the GitHub token when downloading
+// remote media. Hosts of GITHUB_SERVER_URL and GITHUB_API_URL are added for
+// GitHub Enterprise Server.
+var githubHosts = []string{"github.com", "api.github.com"}
+
+// isRemoteMedia reports whether a media path is an http or https URL.
+func isRemoteMedia(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// isGitHubHost reports whether a host belongs to GitHub and may receive the GitHub token.
+func isGitHubHost(host string) bool {
+	host = strings.ToLower(host)
+	if strings.HasSuffix(host, ".githubusercontent.com") {
+		return true
+	}
+
+	hosts := append([]string{}, githubHosts...)
+	for _, env := range []string{"GITHUB_SERVER_URL", "GITHUB_API_URL"} {
+		if u, err := url.Parse(os.Getenv(env)); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(u.Hostname()))
+		}
+	}
+
+	for _, h := range hosts {
+		if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaSourceName returns the file name of a local path or of the path
+// component of a remote media URL.
+func mediaSourceName(source string) string {
+	if isRemoteMedia(source) {
+		if u, err := url.Parse(source); err == nil {
+			return path.Base(u.Path)
+		}
+	}
+	return filepath.Base(source)
+}
+
+// isAcceptedContentType reports whether a downloaded Content-Type matches the
+// expected media kind ("image" or "video"). Generic binary types are accepted
+// because release assets are commonly served that way; the content itself is
+// validated afterwards.
+func isAcceptedContentType(contentType, kind string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch mediaType {
+	case "application/octet-stream", "binary/octet-stream":
+		return true
+	default:
+		return strings.HasPrefix(mediaType, kind+"/")
+	}
+}
+
+// newMediaRequest creates the download request for a remote media URL. The
+// GitHub token is only sent to GitHub hosts over https.
+func newMediaRequest(ctx context.Context, rawURL, githubToken string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media URL %s: %w", rawURL, err)
+	}
+
+	u := request.URL
+	if githubToken != "" && u.Scheme == "https" && isGitHubHost(u.Hostname()) {
+		// Redirects to other hosts, such as release asset storage, drop this header.
+		request.Header.Set("Authorization", "Bearer "+githubToken)
+		if strings.EqualFold(u.Hostname(), "api.github.com") {
+			request.Header.Set("Accept", "application/octet-stream")
+		}
+	}
+
+	return request, nil
+}
+
+// downloadMedia downloads a remote image of at most maxSize bytes into memory
+// and returns its content and Content-Type.
+func downloadMedia(ctx context.Context, rawURL, kind string, maxSize int64, githubToken string, logger *slog.Logger) ([]byte, string, error) {
+	var buf bytes.Buffer
+	contentType, err := downloadMediaTo(ctx, &buf, rawURL, kind, maxSize, githubToken, logger)
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// downloadMediaTo streams a remote image or video of at most maxSize bytes to
+// dst and returns its Content-Type.
+// nolint: errcheck
+func downloadMediaTo(ctx context.Context, dst io.Writer, rawURL, kind string, maxSize int64, githubToken string, logger *slog.Logger) (string, error) {
+	request, err := newMediaRequest(ctx, rawURL, githubToken)
+	if err != nil {
+		return "", err
+	}
+	u := request.URL
+
+	logger.Info("Downloading remote media", "url", u.Redacted())
+
+	client := &http.Client{
+		Timeout: remoteDownloadTimeout,
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s, status code: %d", u.Redacted(), resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !isAcceptedContentType(contentType, kind) {
+		return "", fmt.Errorf("remote %s %s has unexpected content type %s", kind, u.Redacted(), contentType)
+	}
+
+	if resp.ContentLength > maxSize {
+		return "", fmt.Errorf("remote %s %s exceeds maximum size of %d bytes (got %d bytes)", kind, u.Redacted(), maxSize, resp.ContentLength)
+	}
+
+	// Copy one byte past the limit to detect oversized bodies without a Content-Length.
+	size, err := io.Copy(dst, io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", u.Redacted(), err)
+	}
+	if size > maxSize {
+		return "", fmt.Errorf("remote %s %s exceeds maximum size of %d bytes", kind, u.Redacted(), maxSize)
+	}
+
+	logger.Debug("Downloaded remote media", "url", u.Redacted(), "size", size, "contentType", contentType)
+	return contentType, nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIsGitHubHost(t *testing.T) {
+	t.Setenv("GITHUB_SERVER_URL", "https://github.example.com")
+	t.Setenv("GITHUB_API_URL", "https://github.example.com/api/v3")
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "github.com", want: true},
+		{host: "API.GitHub.com", want: true},
+		{host: "objects.githubusercontent.com", want: true},
+		{host: "github.example.com", want: true},
+		{host: "example.com", want: false},
+		{host: "github.com.example.com", want: false},
+		{host: "evilgithub.com", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.host, func(t *testing.T) {
+			if got := isGitHubHost(tc.host); got != tc.want {
+				t.Errorf("isGitHubHost(%q) = %v, want %v", tc.host, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewMediaRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		token      string
+		wantAuth   bool
+		wantAccept string
+	}{
+		{
+			name:     "release download on github.com",
+			url:      "https://github.com/owner/repo/releases/download/v1.0.0/screenshot.png",
+			token:    "ghs_token",
+			wantAuth: true,
+		},
+		{
+			name:       "release asset API",
+			url:        "https://api.github.com/repos/owner/repo/releases/assets/42",
+			token:      "ghs_token",
+			wantAuth:   true,
+			wantAccept: "application/octet-stream",
+		},
+		{
+			name:     "third-party host",
+			url:      "https://example.com/screenshot.png",
+			token:    "ghs_token",
+			wantAuth: false,
+		},
+		{
+			name:     "GitHub over plain http",
+			url:      "http://github.com/owner/repo/raw/main/screenshot.png",
+			token:    "ghs_token",
+			wantAuth: false,
+		},
+		{
+			name:     "no token",
+			url:      "https://github.com/owner/repo/releases/download/v1.0.0/screenshot.png",
+			wantAuth: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := newMediaRequest(context.Background(), tc.url, tc.token)
+			if err != nil {
+				t.Fatalf("newMediaRequest() unexpected error = %v", err)
+			}
+			if got := request.Header.Get("Authorization") != ""; got != tc.wantAuth {
+				t.Errorf("newMediaRequest() sends Authorization = %v, want %v", got, tc.wantAuth)
+			}
+			if got := request.Header.Get("Accept"); got != tc.wantAccept {
+				t.Errorf("newMediaRequest() Accept = %q, want %q", got, tc.wantAccept)
+			}
+		})
+	}
+}
+
+func TestDownloadMedia(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	image := fakeImageData(pngMagic, 64)
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("token sent to non-GitHub host %s", r.Host)
+		}
+
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(image)
+		case "/asset":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(image)
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(make([]byte, 256))
+		case "/chunked.png":
+			w.Header().Set("Content-Type", "image/png")
+			for i := 0; i < 4; i++ {
+				w.Write(make([]byte, 64))
+				w.(http.Flusher).Flush()
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "image", path: "/image.png"},
+		{name: "generic binary content type", path: "/asset"},
+		{name: "HTML page", path: "/page.html", wantErr: true},
+		{name: "Content-Length over limit", path: "/large.png", wantErr: true},
+		{name: "streamed body over limit", path: "/chunked.png", wantErr: true},
+		{name: "not found", path: "/missing.png", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, _, err := downloadMedia(context.Background(), mockServer.URL+tc.path, "image", 128, "ghs_token", logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("downloadMedia() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && len(data) != len(image) {
+				t.Errorf("downloadMedia() returned %d bytes, want %d", len(data), len(image))
+			}
+		})
+	}
+}
+
+func TestProcessImagesRemote(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	mediaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(fakeImageData(pngMagic, 64))
+	}))
+	defer mediaServer.Close()
+
+	var uploaded int
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploaded = len(body)
+		response := map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]interface{}{"$link": "bafkreiremote"},
+				"mimeType": r.Header.Get("Content-Type"),
+				"size":     len(body),
+			},
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer pdsServer.Close()
+
+	// The query string must not be mistaken for a glob pattern.
+	imageURL := mediaServer.URL + "/releases/download/v1.0.0/screenshot?raw=true"
+	result, err := processImages(pdsServer.URL, "fake-token", imageURL, "Release screenshot", ImageOptions{}, logger)
+	if err != nil {
+		t.Fatalf("processImages() unexpected error = %v", err)
+	}
+
+	if len(result.Images) != 1 || result.Images[0].Image.MimeType != "image/png" {
+		t.Fatalf("processImages() = %+v, want one PNG image", result.Images)
+	}
+	if uploaded != 64 {
+		t.Errorf("uploaded %d bytes, want 64", uploaded)
+	}
+}
+
+func TestOpenVideoSourceRemote(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".png") {
+			w.Header().Set("Content-Type", "image/png")
+		} else {
+			w.Header().Set("Content-Type", "video/mp4")
+		}
+		w.Write([]byte("fake video"))
+	}))
+	defer mockServer.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		wantFilename string
+		wantErr      bool
+	}{
+		{
+			name:         "URL with file name",
+			path:         "/releases/download/v1.0.0/demo.mov",
+			wantFilename: "demo.mov",
+		},
+		{
+			name:         "URL without extension uses content type",
+			path:         "/repos/owner/repo/releases/assets/42",
+			wantFilename: "42.mp4",
+		},
+		{
+			name:    "image content type",
+			path:    "/demo.png",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			source, err := openVideoSource(mockServer.URL+tc.path, maxVideoSize, VideoOptions{}, logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("openVideoSource() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			data, err := io.ReadAll(source.file)
+			if err != nil {
+				t.Fatalf("Failed to read downloaded video: %v", err)
+			}
+			if source.filename != tc.wantFilename {
+				t.Errorf("openVideoSource() filename = %s, want %s", source.filename, tc.wantFilename)
+			}
+			if string(data) != "fake video" || source.size != int64(len(data)) {
+				t.Errorf("openVideoSource() data = %q, size %d, want %q", data, source.size, "fake video")
+			}
+
+			tempPath := source.file.Name()
+			if err := source.Close(); err != nil {
+				t.Errorf("Close() unexpected error = %v", err)
+			}
+			if _, err := os.Stat(tempPath); !os.IsNotExist(err) {
+				t.Errorf("downloaded video %s not removed on close", tempPath)
+			}
+		})
+	}
+}
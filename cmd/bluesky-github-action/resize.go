@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+)
+
+// Constants for automatic image resizing.
+const (
+	defaultImageMaxDimension = 2000
+	minImageDimension        = 64
+	resizeStepFactor         = 0.75
+)
+
+// jpegQualities lists the JPEG qualities tried, in order, when re-encoding an image.
+var jpegQualities = []int{90, 80, 70, 60, 50, 40}
+
+// scaleDimensions returns width and height scaled down to fit within maxDimension,
+// preserving the aspect ratio. Dimensions already within the limit are returned unchanged.
+func scaleDimensions(width, height, maxDimension int) (int, int) {
+	if maxDimension <= 0 || (width <= maxDimension && height <= maxDimension) {
+		return width, height
+	}
+
+	if width >= height {
+		scaled := height * maxDimension / width
+		return maxDimension, max(scaled, 1)
+	}
+
+	scaled := width * maxDimension / height
+	return max(scaled, 1), maxDimension
+}
+
+// scaleImage downscales src to the given size by averaging the source pixels
+// covered by each destination pixel.
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba, ok := src.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	}
+
+	srcWidth, srcHeight := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if srcWidth == width && srcHeight == height {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(rgba.Pix[offset])
+					g += int(rgba.Pix[offset+1])
+					b += int(rgba.Pix[offset+2])
+					a += int(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// orientImage applies an EXIF orientation (1-8) to an image so that its pixels
+// are stored upright, as re-encoded images carry no orientation tag.
+func orientImage(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dstWidth, dstHeight := w, h
+	if orientation >= 5 {
+		dstWidth, dstHeight = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally.
+				sx, sy = w-1-x, y
+			case 3: // Rotated 180 degrees.
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirrored vertically.
+				sx, sy = x, h-1-y
+			case 5: // Transposed.
+				sx, sy = y, x
+			case 6: // Rotated 90 degrees clockwise.
+				sx, sy = y, h-1-x
+			case 7: // Transversed.
+				sx, sy = w-1-y, h-1-x
+			case 8: // Rotated 90 degrees counter-clockwise.
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
+
+// isOpaque reports whether an image has no transparent pixels.
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// encodeImage encodes an image as PNG when it has transparency, or as JPEG at
+// descending quality otherwise, returning the first result within maxImageSize.
+func encodeImage(img image.Image, logger *slog.Logger) ([]byte, string, bool, error) {
+	var buf bytes.Buffer
+
+	if !isOpaque(img) {
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, "", false, err
+		}
+		logger.Debug("Encoded image as PNG", "size", buf.Len())
+		return buf.Bytes(), "image/png", buf.Len() <= maxImageSize, nil
+	}
+
+	for _, quality := range jpegQualities {
+		buf.Reset()
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", false, err
+		}
+		logger.Debug("Encoded image as JPEG", "quality", quality, "size", buf.Len())
+		if buf.Len() <= maxImageSize {
+			return buf.Bytes(), "image/jpeg", true, nil
+		}
+	}
+
+	return buf.Bytes(), "image/jpeg", false, nil
+}
+
+// unresizableImageTypes maps image types that cannot be resized, as they have
+// no decoder or would lose their animation, to their display names.
+var unresizableImageTypes = map[string]string{
+	"image/gif":  "GIF",
+	"image/webp": "WebP",
+	"image/avif": "AVIF",
+	"image/heic": "HEIC",
+}
+
+// fitImageToLimit downscales an image to maxDimension and re-encodes it until it
+// fits within maxImageSize. Opaque images are re-encoded as JPEG, images with
+// transparency stay PNG. It returns the new image data and its MIME type.
+func fitImageToLimit(imageData []byte, maxDimension int, logger *slog.Logger) ([]byte, string, error) {
+	if name, ok := unresizableImageTypes[sniffImageMimeType(imageData)]; ok {
+		return nil, "", fmt.Errorf("resizing %s images is not supported", name)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == "jpeg" {
+		img = orientImage(img, jpegOrientation(imageData))
+	}
+
+	if maxDimension <= 0 {
+		maxDimension = defaultImageMaxDimension
+	}
+
+	bounds := img.Bounds()
+	width, height := scaleDimensions(bounds.Dx(), bounds.Dy(), maxDimension)
+
+	for {
+		scaled := scaleImage(img, width, height)
+
+		data, mimeType, fits, err := encodeImage(scaled, logger)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode image: %w", err)
+		}
+
+		if fits {
+			logger.Info("Resized image to fit size limit",
+				"originalSize", len(imageData),
+				"size", len(data),
+				"width", width,
+				"height", height,
+				"mimeType", mimeType,
+			)
+			return data, mimeType, nil
+		}
+
+		nextWidth := int(float64(width) * resizeStepFactor)
+		nextHeight := int(float64(height) * resizeStepFactor)
+		if nextWidth < minImageDimension || nextHeight < minImageDimension {
+			return nil, "", fmt.Errorf("could not reduce image below %d bytes", maxImageSize)
+		}
+		width, height = nextWidth, nextHeight
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log/slog"
+	"math/rand"
+	"testing"
+)
+
+// noisePNG encodes a PNG filled with random pixels, which compresses poorly
+// and therefore produces large files from small dimensions.
+func noisePNG(t *testing.T, width, height int, opaque bool) []byte {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			alpha := uint8(255)
+			if !opaque {
+				alpha = uint8(rng.Intn(256))
+			}
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(rng.Intn(256)),
+				G: uint8(rng.Intn(256)),
+				B: uint8(rng.Intn(256)),
+				A: alpha,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestScaleDimensions(t *testing.T) {
+	tests := []struct {
+		name         string
+		width        int
+		height       int
+		maxDimension int
+		wantWidth    int
+		wantHeight   int
+	}{
+		{
+			name:         "within limit",
+			width:        800,
+			height:       600,
+			maxDimension: 2000,
+			wantWidth:    800,
+			wantHeight:   600,
+		},
+		{
+			name:         "landscape",
+			width:        4000,
+			height:       3000,
+			maxDimension: 2000,
+			wantWidth:    2000,
+			wantHeight:   1500,
+		},
+		{
+			name:         "portrait",
+			width:        1080,
+			height:       2400,
+			maxDimension: 1200,
+			wantWidth:    540,
+			wantHeight:   1200,
+		},
+		{
+			name:         "extreme ratio keeps at least one pixel",
+			width:        10000,
+			height:       1,
+			maxDimension: 100,
+			wantWidth:    100,
+			wantHeight:   1,
+		},
+		{
+			name:         "no limit",
+			width:        4000,
+			height:       3000,
+			maxDimension: 0,
+			wantWidth:    4000,
+			wantHeight:   3000,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotWidth, gotHeight := scaleDimensions(tc.width, tc.height, tc.maxDimension)
+			if gotWidth != tc.wantWidth || gotHeight != tc.wantHeight {
+				t.Errorf("scaleDimensions() = %dx%d, want %dx%d", gotWidth, gotHeight, tc.wantWidth, tc.wantHeight)
+			}
+		})
+	}
+}
+
+func TestFitImageToLimit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("opaque PNG becomes JPEG", func(t *testing.T) {
+		original := noisePNG(t, 1000, 600, true)
+		if len(original) <= maxImageSize {
+			t.Fatalf("test image is %d bytes, want more than %d", len(original), maxImageSize)
+		}
+
+		data, mimeType, err := fitImageToLimit(original, 800, logger)
+		if err != nil {
+			t.Fatalf("fitImageToLimit() unexpected error = %v", err)
+		}
+		if mimeType != "image/jpeg" {
+			t.Errorf("fitImageToLimit() mimeType = %s, want image/jpeg", mimeType)
+		}
+		if len(data) > maxImageSize {
+			t.Errorf("fitImageToLimit() size = %d, want at most %d", len(data), maxImageSize)
+		}
+
+		aspectRatio := getImageDimensions(data, logger)
+		if aspectRatio == nil {
+			t.Fatal("getImageDimensions() returned nil for resized image")
+		}
+		if aspectRatio.Width > 800 || aspectRatio.Height > 800 {
+			t.Errorf("resized image is %dx%d, want at most 800 on each side", aspectRatio.Width, aspectRatio.Height)
+		}
+		if aspectRatio.Width*600 != aspectRatio.Height*1000 {
+			t.Errorf("resized image is %dx%d, want 5:3 aspect ratio", aspectRatio.Width, aspectRatio.Height)
+		}
+	})
+
+	t.Run("transparent PNG stays PNG", func(t *testing.T) {
+		original := noisePNG(t, 800, 800, false)
+		if len(original) <= maxImageSize {
+			t.Fatalf("test image is %d bytes, want more than %d", len(original), maxImageSize)
+		}
+
+		data, mimeType, err := fitImageToLimit(original, 2000, logger)
+		if err != nil {
+			t.Fatalf("fitImageToLimit() unexpected error = %v", err)
+		}
+		if mimeType != "image/png" {
+			t.Errorf("fitImageToLimit() mimeType = %s, want image/png", mimeType)
+		}
+		if len(data) > maxImageSize {
+			t.Errorf("fitImageToLimit() size = %d, want at most %d", len(data), maxImageSize)
+		}
+	})
+
+	unsupported := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "GIF", data: fakeImageData(gifMagic, 1024), wantErr: "resizing GIF images is not supported"},
+		{name: "WebP", data: testWebP("VP8L", []byte{0x2F, 0x00, 0x00, 0x00, 0x00}), wantErr: "resizing WebP images is not supported"},
+		{name: "AVIF", data: testHEIF("avif", 0, [2]uint32{4000, 3000}), wantErr: "resizing AVIF images is not supported"},
+		{name: "HEIC", data: testHEIF("heic", 0, [2]uint32{4000, 3000}), wantErr: "resizing HEIC images is not supported"},
+	}
+	for _, tc := range unsupported {
+		t.Run(tc.name+" is not resized", func(t *testing.T) {
+			_, _, err := fitImageToLimit(tc.data, 2000, logger)
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("fitImageToLimit() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("undecodable data", func(t *testing.T) {
+		if _, _, err := fitImageToLimit(make([]byte, 1024), 2000, logger); err == nil {
+			t.Error("fitImageToLimit() expected error for undecodable data, got nil")
+		}
+	})
+}
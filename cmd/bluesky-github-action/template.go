@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// shortSHALength is the length of abbreviated commit SHAs, as shown by GitHub.
+const shortSHALength = 7
+
+// GitHubRelease represents the release of a GitHub release event.
+type GitHubRelease struct {
+	Name       string `json:"name"`       // Release title.
+	TagName    string `json:"tag_name"`   // Git tag of the release.
+	Body       string `json:"body"`       // Release notes in markdown.
+	URL        string `json:"html_url"`   // Release page URL.
+	Prerelease bool   `json:"prerelease"` // Whether the release is marked as a pre-release.
+}
+
+// GitHubPullRequest represents the pull request of a GitHub pull request event.
+type GitHubPullRequest struct {
+	Number int    `json:"number"`   // Pull request number.
+	Title  string `json:"title"`    // Pull request title.
+	Body   string `json:"body"`     // Pull request description in markdown.
+	URL    string `json:"html_url"` // Pull request page URL.
+}
+
+// GitHubEvent holds the fields of a GitHub event payload used by the action.
+type GitHubEvent struct {
+	Release     *GitHubRelease     `json:"release"`
+	PullRequest *GitHubPullRequest `json:"pull_request"`
+}
+
+// TemplateContext is the data available to the text template.
+type TemplateContext struct {
+	Text          string                 // Value of the text input.
+	Event         map[string]interface{} // Full event payload of GITHUB_EVENT_PATH.
+	EventName     string                 // Name of the triggering event, e.g. release.
+	Repository    string                 // Repository in owner/name form.
+	RepositoryURL string                 // Repository page URL.
+	ServerURL     string                 // GitHub server URL.
+	Ref           string                 // Fully-formed ref, e.g. refs/tags/v1.0.0.
+	RefName       string                 // Short ref name, e.g. v1.0.0.
+	SHA           string                 // Commit SHA that triggered the workflow.
+	Actor         string                 // User that triggered the workflow.
+	Workflow      string                 // Workflow name.
+	RunID         string                 // Workflow run ID.
+	RunURL        string                 // Workflow run page URL.
+	Release       GitHubRelease          // Release of a release event.
+	PullRequest   GitHubPullRequest      // Pull request of a pull request event.
+}
+
+// loadGitHubEvent reads the event payload at path both as a generic map and
+// as the typed fields used by the action. A missing path yields an empty event.
+func loadGitHubEvent(path string) (map[string]interface{}, GitHubEvent, error) {
+	payload := map[string]interface{}{}
+	var event GitHubEvent
+	if path == "" {
+		return payload, event, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, event, fmt.Errorf("failed to read event payload: %w", err)
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, event, fmt.Errorf("failed to parse event payload %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, event, fmt.Errorf("failed to parse event payload %s: %w", path, err)
+	}
+
+	return payload, event, nil
+}
+
+// newTemplateContext builds the template data from the GITHUB_* environment
+// of the workflow run and its event payload.
+func newTemplateContext(text string) (TemplateContext, error) {
+	payload, event, err := loadGitHubEvent(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		return TemplateContext{}, err
+	}
+
+	serverURL := os.Getenv("GITHUB_SERVER_URL")
+	if serverURL == "" {
+		serverURL = "https://github.com"
+	}
+	repository := os.Getenv("GITHUB_REPOSITORY")
+
+	ctx := TemplateContext{
+		Text:       text,
+		Event:      payload,
+		EventName:  os.Getenv("GITHUB_EVENT_NAME"),
+		Repository: repository,
+		ServerURL:  serverURL,
+		Ref:        os.Getenv("GITHUB_REF"),
+		RefName:    os.Getenv("GITHUB_REF_NAME"),
+		SHA:        os.Getenv("GITHUB_SHA"),
+		Actor:      os.Getenv("GITHUB_ACTOR"),
+		Workflow:   os.Getenv("GITHUB_WORKFLOW"),
+		RunID:      os.Getenv("GITHUB_RUN_ID"),
+	}
+	if repository != "" {
+		ctx.RepositoryURL = joinURL(serverURL, repository)
+		if ctx.RunID != "" {
+			ctx.RunURL = joinURL(ctx.RepositoryURL, "actions", "runs", ctx.RunID)
+		}
+	}
+	if event.Release != nil {
+		ctx.Release = *event.Release
+	}
+	if event.PullRequest != nil {
+		ctx.PullRequest = *event.PullRequest
+	}
+
+	return ctx, nil
+}
+
+// joinURL joins a base URL and path segments with single slashes.
+func joinURL(base string, parts ...string) string {
+	joined := strings.TrimRight(base, "/")
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); part != "" {
+			joined += "/" + part
+		}
+	}
+	return joined
+}
+
+// shortSHA abbreviates a commit SHA.
+func shortSHA(sha string) string {
+	if len(sha) > shortSHALength {
+		return sha[:shortSHALength]
+	}
+	return sha
+}
+
+// firstLine returns the first line of a string, such as a commit subject.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimRight(line, "\r")
+}
+
+// templateFuncs are the helper functions available in text templates.
+var templateFuncs = template.FuncMap{
+	"truncate":  func(limit int, s string) string { return truncateGraphemes(s, limit) },
+	"shortSHA":  shortSHA,
+	"joinURL":   joinURL,
+	"firstLine": firstLine,
+	"trim":      strings.TrimSpace,
+}
+
+// renderTemplate renders a post text template. Referencing a missing key of
+// the event payload is an error rather than printing "<no value>".
+func renderTemplate(text string, data TemplateContext) (string, error) {
+	tmpl, err := template.New("text-template").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid text template: %w", err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("failed to render text template: %w", err)
+	}
+
+	return strings.TrimSpace(rendered.String()), nil
+}
+
+// renderPostText returns the post text, rendering the text template against
+// the GitHub context when one is given.
+func renderPostText(args ActionInputs) (string, error) {
+	if args.TextTemplate == "" {
+		return args.Text, nil
+	}
+
+	data, err := newTemplateContext(args.Text)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(args.TextTemplate, data)
+}
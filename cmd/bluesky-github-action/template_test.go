@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useGitHubContext sets the GITHUB_* environment of a release event workflow
+// run for the duration of a test.
+func useGitHubContext(t *testing.T, payload string) {
+	t.Helper()
+
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(eventPath, []byte(payload), 0644); err != nil {
+		t.Fatalf("Failed to write event payload: %v", err)
+	}
+
+	t.Setenv("GITHUB_EVENT_PATH", eventPath)
+	t.Setenv("GITHUB_EVENT_NAME", "release")
+	t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	t.Setenv("GITHUB_REPOSITORY", "cbrgm/bluesky-github-action")
+	t.Setenv("GITHUB_REF", "refs/tags/v1.2.0")
+	t.Setenv("GITHUB_REF_NAME", "v1.2.0")
+	t.Setenv("GITHUB_SHA", "4f2a9c1e8b7d6f5a4c3b2a1908f7e6d5c4b3a291")
+	t.Setenv("GITHUB_ACTOR", "octocat")
+	t.Setenv("GITHUB_WORKFLOW", "Release")
+	t.Setenv("GITHUB_RUN_ID", "42")
+	t.Setenv("GITHUB_JOB", "announce")
+	t.Setenv("GITHUB_ACTION", "bluesky_post")
+}
+
+const testReleasePayload = `{
+	"action": "published",
+	"release": {
+		"name": "v1.2.0 \"Aurora\"",
+		"tag_name": "v1.2.0",
+		"body": "Faster uploads\nand more",
+		"html_url": "https://github.com/cbrgm/bluesky-github-action/releases/tag/v1.2.0",
+		"prerelease": false
+	},
+	"repository": {"stargazers_count": 128}
+}`
+
+func TestRenderPostText(t *testing.T) {
+	useGitHubContext(t, testReleasePayload)
+
+	tests := []struct {
+		name     string
+		args     ActionInputs
+		want     string
+		wantErr  bool
+		noGitHub bool
+	}{
+		{
+			name: "text without template",
+			args: ActionInputs{Text: "Hello {{ .Actor }}"},
+			want: "Hello {{ .Actor }}",
+		},
+		{
+			name: "release fields keep quotes and newlines",
+			args: ActionInputs{TextTemplate: "{{ .Release.Name }}: {{ .Release.Body }}\n{{ .Release.URL }}\n"},
+			want: "v1.2.0 \"Aurora\": Faster uploads\nand more\nhttps://github.com/cbrgm/bluesky-github-action/releases/tag/v1.2.0",
+		},
+		{
+			name: "workflow context and helpers",
+			args: ActionInputs{TextTemplate: `{{ .Repository }}@{{ shortSHA .SHA }} by {{ .Actor }} {{ joinURL .RepositoryURL "commit" .SHA }} {{ .RunURL }}`},
+			want: "cbrgm/bluesky-github-action@4f2a9c1 by octocat https://github.com/cbrgm/bluesky-github-action/commit/4f2a9c1e8b7d6f5a4c3b2a1908f7e6d5c4b3a291 https://github.com/cbrgm/bluesky-github-action/actions/runs/42",
+		},
+		{
+			name: "truncate and first line",
+			args: ActionInputs{TextTemplate: `{{ .Release.Body | firstLine | truncate 7 }}`},
+			want: "Faster…",
+		},
+		{
+			name: "raw event payload and text input",
+			args: ActionInputs{Text: "Stars:", TextTemplate: `{{ .Text }} {{ .Event.repository.stargazers_count }} ({{ .Event.action }})`},
+			want: "Stars: 128 (published)",
+		},
+		{
+			name:    "missing event key",
+			args:    ActionInputs{TextTemplate: `{{ .Event.pull_request }}`},
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			args:    ActionInputs{TextTemplate: `{{ .Release.Name`},
+			wantErr: true,
+		},
+		{
+			name:     "outside of GitHub Actions",
+			args:     ActionInputs{TextTemplate: `Released {{ .RefName }}{{ .Release.Name }}`},
+			want:     "Released",
+			noGitHub: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.noGitHub {
+				t.Setenv("GITHUB_EVENT_PATH", "")
+				t.Setenv("GITHUB_REF_NAME", "")
+			}
+
+			got, err := renderPostText(tc.args)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("renderPostText() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("renderPostText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderPostTextInvalidEvent(t *testing.T) {
+	useGitHubContext(t, `{"release": `)
+
+	if _, err := renderPostText(ActionInputs{TextTemplate: "{{ .RefName }}"}); err == nil {
+		t.Error("renderPostText() expected error for invalid event payload")
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		parts []string
+		want  string
+	}{
+		{name: "no parts", base: "https://github.com/", want: "https://github.com"},
+		{name: "slashes normalized", base: "https://github.com/", parts: []string{"/cbrgm/repo/", "releases", "", "tag/v1"}, want: "https://github.com/cbrgm/repo/releases/tag/v1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := joinURL(tc.base, tc.parts...); got != tc.want {
+				t.Errorf("joinURL() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
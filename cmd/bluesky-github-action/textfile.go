@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTextFileSize is the maximum size of a post text file. Posts are far
+// shorter, but the text may be shortened by a template.
+const maxTextFileSize = 1 << 20 // 1MB
+
+// stdinTextFile is the text-file value that reads the post text from stdin.
+const stdinTextFile = "-"
+
+// readTextFile reads UTF-8 post text from a file, or from stdin when path is
+// "-". A byte order mark is removed, line endings are normalized to "\n", and
+// a single trailing newline is stripped.
+func readTextFile(path string, stdin io.Reader) (string, error) {
+	name := path
+	r := stdin
+	if path == stdinTextFile {
+		name = "stdin"
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to open text file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxTextFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read text from %s: %w", name, err)
+	}
+	if len(data) > maxTextFileSize {
+		return "", fmt.Errorf("text from %s exceeds maximum size of %d bytes", name, maxTextFileSize)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("text from %s is not valid UTF-8", name)
+	}
+
+	text := string(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.TrimSuffix(text, "\n"), nil
+}
+
+// loadText returns the text input, read from the text file when one is given.
+func loadText(args ActionInputs, stdin io.Reader) (string, error) {
+	if args.TextFile == "" {
+		return args.Text, nil
+	}
+	if args.Text != "" {
+		return "", fmt.Errorf("text and text-file cannot be used together")
+	}
+	return readTextFile(args.TextFile, stdin)
+}
+
+// postText returns the text of the post from the text or text file inputs,
+// followed by the summary of the release in release notes mode, and rendered
+// by the text template when one is given.
+func postText(args ActionInputs, release *GitHubRelease, stdin io.Reader) (string, error) {
+	text, err := loadText(args, stdin)
+	if err != nil {
+		return "", err
+	}
+	if release != nil {
+		text = releaseNotesText(text, *release, args.ReleaseNotesBullets)
+	}
+
+	args.Text = text
+	if text, err = renderPostText(args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("post text is empty, set text, text-file, or text-template")
+	}
+
+	return text, nil
+}
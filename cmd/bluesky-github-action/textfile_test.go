@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadTextFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "multi-line changelog", content: "v1.2.0 \"Aurora\"\n\n- Faster uploads\n- Captions\n", want: "v1.2.0 \"Aurora\"\n\n- Faster uploads\n- Captions"},
+		{name: "CRLF line endings", content: "Line one\r\nLine two\r\n", want: "Line one\nLine two"},
+		{name: "CR line endings", content: "Line one\rLine two", want: "Line one\nLine two"},
+		{name: "only one trailing newline stripped", content: "Text\n\n", want: "Text\n"},
+		{name: "byte order mark", content: "\xEF\xBB\xBFText", want: "Text"},
+		{name: "invalid UTF-8", content: "caf\xe9", wantErr: true},
+		{name: "too large", content: strings.Repeat("a", maxTextFileSize+1), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "post.txt")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write text file: %v", err)
+			}
+
+			got, err := readTextFile(path, nil)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("readTextFile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("readTextFile() = %q, want %q", got, tc.want)
+			}
+
+			// Text piped through stdin is read the same way.
+			got, err = readTextFile(stdinTextFile, strings.NewReader(tc.content))
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("readTextFile(stdin) = %q, %v, want %q, wantErr %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+
+	if _, err := readTextFile(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("readTextFile() expected error for missing file")
+	}
+}
+
+func TestPostText(t *testing.T) {
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	t.Setenv("GITHUB_REPOSITORY", "cbrgm/bluesky-github-action")
+
+	tests := []struct {
+		name    string
+		args    ActionInputs
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "text input", args: ActionInputs{Text: "Hello"}, want: "Hello"},
+		{name: "stdin", args: ActionInputs{TextFile: "-"}, stdin: "From stdin\n", want: "From stdin"},
+		{
+			name:  "file text rendered by template",
+			args:  ActionInputs{TextFile: "-", TextTemplate: "{{ .Repository }}: {{ .Text }}"},
+			stdin: "Changelog\n",
+			want:  "cbrgm/bluesky-github-action: Changelog",
+		},
+		{name: "text and text file", args: ActionInputs{Text: "Hello", TextFile: "-"}, wantErr: true},
+		{name: "no text", args: ActionInputs{}, wantErr: true},
+		{name: "blank file", args: ActionInputs{TextFile: "-"}, stdin: " \n\n", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := postText(tc.args, nil, strings.NewReader(tc.stdin))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("postText() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("postText() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,12 +19,69 @@ import (
 
 // Constants for video upload constraints.
 const (
-	videoServiceURL      = "https://video.bsky.app"
-	maxVideoSize         = 50 * 1024 * 1024 // 50MB in bytes (reasonable default)
-	videoStatusPollDelay = 2 * time.Second
-	videoStatusMaxWait   = 5 * time.Minute
+	maxVideoSize         = 50 * 1024 * 1024 // 50MB in bytes, used when the service reports no limit
+	maxVideoDuration     = 3 * time.Minute  // Longest video accepted by the video service.
+	videoStatusMaxWait   = 5 * time.Minute  // Default time to wait for video processing.
+	videoStatusMaxErrors = 3                // Consecutive transient job status errors tolerated.
+	maxDirectVideoSize   = 5 * 1024 * 1024  // Default blob size limit of a PDS, for uploads bypassing the video service.
 )
 
+// Policies for handling an unavailable video service.
+const (
+	videoFallbackNone = "none" // Fail the action.
+	videoFallbackBlob = "blob" // Upload the video directly to the PDS.
+	videoFallbackDrop = "drop" // Post without the video.
+)
+
+// Delays between video job status requests, doubling from the first to the
+// longest delay.
+var (
+	videoStatusPollDelay    = 2 * time.Second
+	videoStatusMaxPollDelay = 15 * time.Second
+)
+
+// Lexicon methods that service auth tokens are scoped to.
+const (
+	lxmUploadBlob      = "com.atproto.repo.uploadBlob"
+	lxmGetUploadLimits = "app.bsky.video.getUploadLimits"
+)
+
+// videoServiceURL is the base URL of the Bluesky video service.
+var videoServiceURL = "https://video.bsky.app"
+
+// VideoOptions controls how videos are read and uploaded.
+type VideoOptions struct {
+	AltText     AltTextPolicy // Handling of missing and overlong alt texts.
+	GitHubToken string        // Token sent when downloading remote videos from GitHub.
+	Captions    string        // Comma-separated "lang=path" WebVTT caption files.
+	MaxDuration time.Duration // Longest accepted video; zero uses maxVideoDuration.
+	MaxWait     time.Duration // Time to wait for video processing; zero uses videoStatusMaxWait.
+	Fallback    string        // Handling of an unavailable video service: none, blob, or drop.
+}
+
+// videoServiceError marks a failure of the video service or of the service
+// authentication, as opposed to an invalid video. The fallback policy applies
+// only to these errors.
+type videoServiceError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *videoServiceError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *videoServiceError) Unwrap() error {
+	return e.err
+}
+
+// isVideoServiceError reports whether err is caused by the video service.
+func isVideoServiceError(err error) bool {
+	var serviceErr *videoServiceError
+	return errors.As(err, &serviceErr)
+}
+
 // ServiceAuthResponse represents the response from getServiceAuth.
 type ServiceAuthResponse struct {
 	Token string `json:"token"`
@@ -33,6 +93,15 @@ type VideoUploadResponse struct {
 	Status *VideoJobStatus `json:"jobStatus,omitempty"`
 }
 
+// VideoUploadLimits represents the response from getUploadLimits.
+type VideoUploadLimits struct {
+	CanUpload            bool   `json:"canUpload"`
+	RemainingDailyVideos int64  `json:"remainingDailyVideos,omitempty"`
+	RemainingDailyBytes  int64  `json:"remainingDailyBytes,omitempty"`
+	Message              string `json:"message,omitempty"`
+	Error                string `json:"error,omitempty"`
+}
+
 // VideoJobStatus represents the status of a video processing job.
 type VideoJobStatus struct {
 	JobID    string `json:"jobId"`
@@ -43,6 +112,28 @@ type VideoJobStatus struct {
 	Message  string `json:"message,omitempty"`
 }
 
+// jobStatusError is returned when the video service responds to a job status
+// request with an error status code.
+type jobStatusError struct {
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *jobStatusError) Error() string {
+	return fmt.Sprintf("failed to get job status, status code: %d", e.StatusCode)
+}
+
+// isTransientJobStatusError reports whether a failed job status request may
+// succeed when repeated. Network errors, rate limits, and server errors are
+// transient; other client errors are not.
+func isTransientJobStatusError(err error) bool {
+	var statusErr *jobStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return true
+}
+
 // detectVideoMimeType detects the MIME type based on file extension.
 func detectVideoMimeType(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -58,10 +149,110 @@ func detectVideoMimeType(filename string) string {
 	}
 }
 
+// videoExtensions maps supported video MIME types to a file extension.
+var videoExtensions = map[string]string{
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"video/webm":      ".webm",
+}
+
+// videoSource is an opened video file, read from disk during upload instead of
+// being held in memory.
+type videoSource struct {
+	file     *os.File
+	filename string // File name with an extension matching the video format.
+	size     int64
+	temp     bool // Whether file is a downloaded copy to remove on close.
+}
+
+// Close closes the video file and removes it if it was downloaded.
+func (v *videoSource) Close() error {
+	err := v.file.Close()
+	if v.temp {
+		os.Remove(v.file.Name()) // nolint: errcheck
+	}
+	return err
+}
+
+// openVideoSource opens a local video file, or downloads a remote video URL to
+// a temporary file and opens that.
+func openVideoSource(path string, maxSize int64, opts VideoOptions, logger *slog.Logger) (*videoSource, error) {
+	if !isRemoteMedia(path) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read video file %s: %w", path, err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close() // nolint: errcheck
+			return nil, fmt.Errorf("failed to read video file %s: %w", path, err)
+		}
+		return &videoSource{file: file, filename: mediaSourceName(path), size: info.Size()}, nil
+	}
+
+	file, err := os.CreateTemp("", "bluesky-video-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary video file: %w", err)
+	}
+	source := &videoSource{file: file, filename: mediaSourceName(path), temp: true}
+
+	contentType, err := downloadMediaTo(context.Background(), file, path, "video", maxSize, opts.GitHubToken, logger)
+	if err != nil {
+		source.Close() // nolint: errcheck
+		return nil, err
+	}
+
+	if source.size, err = file.Seek(0, io.SeekCurrent); err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		source.Close() // nolint: errcheck
+		return nil, fmt.Errorf("failed to rewind downloaded video: %w", err)
+	}
+
+	// URLs without a file extension, such as API download URLs, take it from the Content-Type.
+	if detectVideoMimeType(source.filename) == "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && videoExtensions[mediaType] != "" {
+			source.filename += videoExtensions[mediaType]
+		}
+	}
+
+	return source, nil
+}
+
+// progressReader reports the progress of reading a stream of known size to
+// the log in steps of progressStep percent.
+type progressReader struct {
+	reader io.Reader
+	total  int64
+	read   int64
+	next   int64 // Next percentage to report.
+	logger *slog.Logger
+}
+
+// progressStep is the percentage between two progress log messages.
+const progressStep = 25
+
+// Read reads from the underlying reader and logs each completed progress step.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+
+	if r.total > 0 {
+		percent := r.read * 100 / r.total
+		if percent >= r.next {
+			r.logger.Info("Video upload progress", "percent", percent, "bytes", r.read, "total", r.total)
+			r.next = (percent/progressStep + 1) * progressStep
+		}
+	}
+
+	return n, err
+}
+
 // validateVideoData validates video file size and format.
-func validateVideoData(path string, videoData []byte) error {
-	if len(videoData) > maxVideoSize {
-		return fmt.Errorf("video %s exceeds maximum size of %d bytes (got %d bytes)", path, maxVideoSize, len(videoData))
+func validateVideoData(path string, size, maxSize int64) error {
+	if size > maxSize {
+		return fmt.Errorf("video %s exceeds maximum size of %d bytes (got %d bytes)", path, maxSize, size)
 	}
 
 	mimeType := detectVideoMimeType(path)
@@ -72,9 +263,10 @@ func validateVideoData(path string, videoData []byte) error {
 	return nil
 }
 
-// getServiceAuthToken creates a service authentication token for video upload.
+// getServiceAuthToken creates a service authentication token for the video
+// service, scoped to the given lexicon method.
 // nolint: errcheck
-func getServiceAuthToken(pdsURL, accessToken, userDID string, logger *slog.Logger) (string, error) {
+func getServiceAuthToken(pdsURL, accessToken, userDID, lxm string, logger *slog.Logger) (string, error) {
 	// Extract host from video service URL for audience
 	videoURL, err := url.Parse(videoServiceURL)
 	if err != nil {
@@ -88,7 +280,7 @@ func getServiceAuthToken(pdsURL, accessToken, userDID string, logger *slog.Logge
 
 	reqBody := map[string]interface{}{
 		"aud": audience,
-		"lxm": "com.atproto.repo.uploadBlob",
+		"lxm": lxm,
 		"exp": expiryTime,
 	}
 
@@ -129,23 +321,106 @@ func getServiceAuthToken(pdsURL, accessToken, userDID string, logger *slog.Logge
 	return authResp.Token, nil
 }
 
+// getVideoUploadLimits queries how many videos and bytes the account may still upload today.
+// nolint: errcheck
+func getVideoUploadLimits(serviceToken string, logger *slog.Logger) (*VideoUploadLimits, error) {
+	limitsURL := fmt.Sprintf("%s/xrpc/app.bsky.video.getUploadLimits", videoServiceURL)
+
+	request, err := http.NewRequest("GET", limitsURL, nil)
+	if err != nil {
+		logger.Error("Error creating upload limits request", "err", err)
+		return nil, err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get upload limits, status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var limits VideoUploadLimits
+	if err := json.NewDecoder(resp.Body).Decode(&limits); err != nil {
+		return nil, fmt.Errorf("failed to decode upload limits: %w", err)
+	}
+
+	return &limits, nil
+}
+
+// checkVideoUploadLimits fails fast when the account cannot upload videos and
+// returns the maximum video size to accept: maxVideoSize, or the remaining
+// daily upload quota when it is smaller. When the limits cannot be queried,
+// the upload is attempted with maxVideoSize.
+func checkVideoUploadLimits(pdsURL, accessToken, userDID string, logger *slog.Logger) (int64, error) {
+	limitsToken, err := getServiceAuthToken(pdsURL, accessToken, userDID, lxmGetUploadLimits, logger)
+	if err != nil {
+		logger.Warn("Could not get service auth token for upload limits, skipping check", "err", err)
+		return maxVideoSize, nil
+	}
+
+	limits, err := getVideoUploadLimits(limitsToken, logger)
+	if err != nil {
+		logger.Warn("Could not query video upload limits, skipping check", "err", err)
+		return maxVideoSize, nil
+	}
+
+	logger.Info("Video upload limits",
+		"canUpload", limits.CanUpload,
+		"remainingDailyVideos", limits.RemainingDailyVideos,
+		"remainingDailyBytes", limits.RemainingDailyBytes,
+		"message", limits.Message,
+	)
+
+	if !limits.CanUpload {
+		reason := limits.Message
+		if reason == "" {
+			reason = limits.Error
+		}
+		if reason == "" {
+			reason = "the account is not allowed to upload videos"
+		}
+		return 0, fmt.Errorf("video upload not allowed: %s", reason)
+	}
+
+	if limits.RemainingDailyBytes > 0 {
+		return min(maxVideoSize, limits.RemainingDailyBytes), nil
+	}
+	return maxVideoSize, nil
+}
+
+// checkVideoQuota fails when a video is larger than the remaining daily upload
+// quota, which is the size limit when it is below maxVideoSize.
+func checkVideoQuota(path string, size, maxSize int64) error {
+	if maxSize < maxVideoSize && size > maxSize {
+		return fmt.Errorf("video %s (%d bytes) exceeds the remaining daily video upload quota of %d bytes", path, size, maxSize)
+	}
+	return nil
+}
+
 // uploadVideoToService uploads a video to the Bluesky video service.
 // nolint: errcheck
-func uploadVideoToService(userDID, serviceToken string, videoData []byte, filename, mimeType string, logger *slog.Logger) (*VideoUploadResponse, error) {
+func uploadVideoToService(userDID, serviceToken string, video io.Reader, size int64, filename, mimeType string, logger *slog.Logger) (*VideoUploadResponse, error) {
 	uploadURL := fmt.Sprintf("%s/xrpc/app.bsky.video.uploadVideo?did=%s&name=%s",
 		videoServiceURL,
 		url.QueryEscape(userDID),
 		url.QueryEscape(filename),
 	)
 
-	request, err := http.NewRequest("POST", uploadURL, bytes.NewBuffer(videoData))
+	request, err := http.NewRequest("POST", uploadURL, video)
 	if err != nil {
 		logger.Error("Error creating video upload request", "err", err)
 		return nil, err
 	}
 
+	request.ContentLength = size
 	request.Header.Set("Content-Type", mimeType)
-	request.Header.Set("Content-Length", fmt.Sprintf("%d", len(videoData)))
 	request.Header.Set("Authorization", "Bearer "+serviceToken)
 
 	client := &http.Client{
@@ -174,14 +449,13 @@ func uploadVideoToService(userDID, serviceToken string, videoData []byte, filena
 }
 
 // getVideoJobStatus polls for the video processing job status.
-// nolint: errcheck
-func getVideoJobStatus(serviceToken, jobID string, logger *slog.Logger) (*VideoJobStatus, error) {
+func getVideoJobStatus(ctx context.Context, serviceToken, jobID string, logger *slog.Logger) (*VideoJobStatus, error) {
 	statusURL := fmt.Sprintf("%s/xrpc/app.bsky.video.getJobStatus?jobId=%s",
 		videoServiceURL,
 		url.QueryEscape(jobID),
 	)
 
-	request, err := http.NewRequest("GET", statusURL, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", statusURL, nil)
 	if err != nil {
 		logger.Error("Error creating job status request", "err", err)
 		return nil, err
@@ -192,7 +466,7 @@ func getVideoJobStatus(serviceToken, jobID string, logger *slog.Logger) (*VideoJ
 	client := &http.Client{}
 	resp, err := client.Do(request)
 	if err != nil {
-		logger.Error("Error getting job status", "err", err)
+		logger.Debug("Error getting job status", "err", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -212,119 +486,284 @@ func getVideoJobStatus(serviceToken, jobID string, logger *slog.Logger) (*VideoJ
 				return errorResp.Status, nil
 			}
 		}
-		logger.Error("Failed to get job status", "statusCode", resp.StatusCode, "body", string(body))
-		return nil, fmt.Errorf("failed to get job status, status code: %d", resp.StatusCode)
+		logger.Debug("Failed to get job status", "statusCode", resp.StatusCode, "body", string(body))
+		return nil, &jobStatusError{StatusCode: resp.StatusCode}
 	}
 
 	var statusResp struct {
 		JobStatus VideoJobStatus `json:"jobStatus"`
 	}
 	if err := json.Unmarshal(body, &statusResp); err != nil {
-		logger.Error("Error decoding job status response", "err", err)
+		logger.Debug("Error decoding job status response", "err", err)
 		return nil, err
 	}
 
 	return &statusResp.JobStatus, nil
 }
 
-// pollVideoJobUntilComplete polls the video job status until it's complete or times out.
-func pollVideoJobUntilComplete(serviceToken, jobID string, logger *slog.Logger) (*Blob, error) {
-	startTime := time.Now()
-
-	for {
-		// Check timeout
-		if time.Since(startTime) > videoStatusMaxWait {
-			return nil, fmt.Errorf("video processing timed out after %v", videoStatusMaxWait)
-		}
+// videoProcessingContext returns a context that expires after the configured
+// time to wait for video processing.
+func videoProcessingContext(opts VideoOptions) (context.Context, context.CancelFunc) {
+	maxWait := opts.MaxWait
+	if maxWait <= 0 {
+		maxWait = videoStatusMaxWait
+	}
+	return context.WithTimeoutCause(context.Background(), maxWait, fmt.Errorf("video processing timed out after %v", maxWait))
+}
 
-		status, err := getVideoJobStatus(serviceToken, jobID, logger)
-		if err != nil {
-			return nil, err
-		}
+// pollVideoJobUntilComplete polls the video job status until it's complete or
+// ctx is done. The delay between polls doubles up to videoStatusMaxPollDelay,
+// and up to videoStatusMaxErrors consecutive transient errors are retried.
+func pollVideoJobUntilComplete(ctx context.Context, serviceToken, jobID string, logger *slog.Logger) (*Blob, error) {
+	delay := videoStatusPollDelay
+	lastProgress := -1
+	errorCount := 0
 
-		logger.Debug("Video processing status",
-			"jobId", jobID,
-			"state", status.State,
-			"progress", status.Progress,
-		)
+	for {
+		status, err := getVideoJobStatus(ctx, serviceToken, jobID, logger)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, &videoServiceError{err: context.Cause(ctx)}
+		case err != nil:
+			errorCount++
+			if !isTransientJobStatusError(err) || errorCount > videoStatusMaxErrors {
+				return nil, &videoServiceError{err: err}
+			}
+			logger.Warn("Failed to get video job status, retrying", "jobId", jobID, "attempt", errorCount, "err", err)
+		default:
+			errorCount = 0
+
+			// Check if blob is ready
+			if status.Blob != nil {
+				logger.Info("Video processing complete", "jobId", jobID)
+				return status.Blob, nil
+			}
 
-		// Check if blob is ready
-		if status.Blob != nil {
-			logger.Info("Video processing complete", "jobId", jobID)
-			return status.Blob, nil
-		}
+			// Check for error state
+			if status.State == "failed" || status.Error != "" {
+				return nil, fmt.Errorf("video processing failed: %s", status.Error)
+			}
 
-		// Check for error state
-		if status.State == "failed" || status.Error != "" {
-			return nil, fmt.Errorf("video processing failed: %s", status.Error)
+			if status.Progress != lastProgress {
+				logger.Info("Video processing progress", "jobId", jobID, "state", status.State, "percent", status.Progress)
+				lastProgress = status.Progress
+			}
 		}
 
 		// Wait before next poll
-		time.Sleep(videoStatusPollDelay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &videoServiceError{err: context.Cause(ctx)}
+		case <-timer.C:
+		}
+		delay = min(delay*2, videoStatusMaxPollDelay)
 	}
 }
 
-// processVideo processes a single video file: reads, validates, uploads, and creates an embed.
-func processVideo(pdsURL, accessToken, userDID, path, altText string, logger *slog.Logger) (*EmbedVideo, error) {
-	logger.Info("Processing video", "path", path)
+// videoMaxDuration returns the configured duration limit, capped at the
+// maximum of the video service.
+func videoMaxDuration(opts VideoOptions, logger *slog.Logger) time.Duration {
+	if opts.MaxDuration <= 0 {
+		return maxVideoDuration
+	}
+	if opts.MaxDuration > maxVideoDuration {
+		logger.Warn("Video duration limit exceeds service maximum, using service maximum", "limit", opts.MaxDuration, "max", maxVideoDuration)
+		return maxVideoDuration
+	}
+	return opts.MaxDuration
+}
 
-	// Read video file
-	videoData, err := os.ReadFile(path)
+// validateVideoContent reads the container headers of a video to verify that
+// its content matches its extension and that it is not longer than maxDuration.
+func validateVideoContent(source *videoSource, maxDuration time.Duration, logger *slog.Logger) (*VideoMetadata, error) {
+	meta, err := probeVideo(source.file, source.size)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read video file %s: %w", path, err)
+		return nil, fmt.Errorf("video %s is not a valid MP4, MOV, or WebM file: %w", source.filename, err)
 	}
 
-	// Validate video
-	if err := validateVideoData(path, videoData); err != nil {
-		return nil, err
+	if videoExtensions[meta.MimeType] == "" {
+		return nil, fmt.Errorf("unsupported video format %s for file %s (supported: MP4, MOV, WebM)", meta.MimeType, source.filename)
 	}
 
-	mimeType := detectVideoMimeType(path)
-	filename := filepath.Base(path)
+	// MP4 and MOV share a container format and are often mislabeled, so only WebM must match.
+	if extType := detectVideoMimeType(source.filename); (extType == "video/webm") != (meta.MimeType == "video/webm") {
+		return nil, fmt.Errorf("video %s content is %s, which does not match its extension", source.filename, meta.MimeType)
+	}
+
+	switch {
+	case meta.Duration == 0:
+		logger.Warn("Could not determine video duration", "path", source.filename)
+	case meta.Duration > maxDuration:
+		return nil, fmt.Errorf("video %s exceeds maximum duration of %s (got %s)", source.filename, maxDuration, meta.Duration.Round(time.Second/10))
+	}
+
+	logger.Debug("Video metadata", "mimeType", meta.MimeType, "duration", meta.Duration, "width", meta.Width, "height", meta.Height)
+	return meta, nil
+}
+
+// videoAspectRatio returns the aspect ratio of the video display size, or nil
+// if the container does not declare it.
+func videoAspectRatio(meta *VideoMetadata) *AspectRatio {
+	if meta.Width <= 0 || meta.Height <= 0 {
+		return nil
+	}
+	return &AspectRatio{
+		Width:  meta.Width,
+		Height: meta.Height,
+	}
+}
 
+// uploadVideoViaService uploads a video to the video service and waits until
+// it is processed. Failures of the service are returned as videoServiceError.
+func uploadVideoViaService(pdsURL, accessToken, userDID string, source *videoSource, mimeType string, opts VideoOptions, logger *slog.Logger) (*Blob, error) {
 	logger.Info("Getting service auth token for video upload")
 
 	// Get service auth token
-	serviceToken, err := getServiceAuthToken(pdsURL, accessToken, userDID, logger)
+	serviceToken, err := getServiceAuthToken(pdsURL, accessToken, userDID, lxmUploadBlob, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service auth token: %w", err)
+		return nil, &videoServiceError{err: fmt.Errorf("failed to get service auth token: %w", err)}
 	}
 
-	logger.Info("Uploading video to service", "size", len(videoData), "mimeType", mimeType)
+	logger.Info("Uploading video to service", "size", source.size, "mimeType", mimeType)
 
-	// Upload video
-	uploadResp, err := uploadVideoToService(userDID, serviceToken, videoData, filename, mimeType, logger)
+	// Stream the video from disk
+	body := &progressReader{
+		reader: source.file,
+		total:  source.size,
+		logger: logger,
+	}
+
+	uploadResp, err := uploadVideoToService(userDID, serviceToken, body, source.size, source.filename, mimeType, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload video: %w", err)
+		return nil, &videoServiceError{err: fmt.Errorf("failed to upload video: %w", err)}
 	}
 
+	logger.Debug("Video upload complete", "jobId", uploadResp.JobID)
+
 	// Check if blob is immediately available (already processed)
 	if uploadResp.Status != nil && uploadResp.Status.Blob != nil {
 		logger.Info("Video already processed, using existing blob")
-		return &EmbedVideo{
-			Type:  "app.bsky.embed.video",
-			Video: *uploadResp.Status.Blob,
-			Alt:   altText,
-		}, nil
+		return uploadResp.Status.Blob, nil
 	}
 
 	logger.Info("Video uploaded, waiting for processing", "jobId", uploadResp.JobID)
 
 	// Poll for processing completion
-	blob, err := pollVideoJobUntilComplete(serviceToken, uploadResp.JobID, logger)
+	ctx, cancel := videoProcessingContext(opts)
+	defer cancel()
+
+	blob, err := pollVideoJobUntilComplete(ctx, serviceToken, uploadResp.JobID, logger)
 	if err != nil {
 		return nil, fmt.Errorf("video processing failed: %w", err)
 	}
+	return blob, nil
+}
+
+// uploadVideoDirect uploads a small video as a blob to the PDS, bypassing the
+// video service.
+func uploadVideoDirect(pdsURL, accessToken string, source *videoSource, mimeType string, logger *slog.Logger) (*Blob, error) {
+	if source.size > maxDirectVideoSize {
+		return nil, fmt.Errorf("video %s exceeds maximum size of %d bytes for direct upload (got %d bytes)", source.filename, maxDirectVideoSize, source.size)
+	}
+
+	// A failed service upload may have read part of the file.
+	data, err := io.ReadAll(io.NewSectionReader(source.file, 0, source.size))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read video file %s: %w", source.filename, err)
+	}
+
+	logger.Info("Uploading video directly to PDS", "size", len(data), "mimeType", mimeType)
+	return uploadBlob(pdsURL, accessToken, data, mimeType, logger)
+}
+
+// processVideo processes a single video file: reads, validates, uploads, and creates an embed.
+func processVideo(pdsURL, accessToken, userDID, path, altText string, opts VideoOptions, logger *slog.Logger) (*EmbedVideo, error) {
+	logger.Info("Processing video", "path", path)
+
+	// Validate captions before the video is uploaded
+	captionFiles, err := loadCaptions(opts, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the account may upload videos before downloading a remote video.
+	// Local videos are validated first, so corrupt files fail without any request.
+	remote := isRemoteMedia(path)
+	maxSize := int64(maxVideoSize)
+	if remote {
+		if maxSize, err = checkVideoUploadLimits(pdsURL, accessToken, userDID, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	// Open video file or download remote video
+	source, err := openVideoSource(path, maxVideoSize, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close() // nolint: errcheck
+
+	// Validate video
+	if err := validateVideoData(source.filename, source.size, maxVideoSize); err != nil {
+		return nil, err
+	}
+
+	// Verify the format and duration from the content before anything is uploaded
+	meta, err := validateVideoContent(source, videoMaxDuration(opts, logger), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if !remote {
+		if maxSize, err = checkVideoUploadLimits(pdsURL, accessToken, userDID, logger); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkVideoQuota(source.filename, source.size, maxSize); err != nil {
+		return nil, err
+	}
+
+	blob, err := uploadVideoViaService(pdsURL, accessToken, userDID, source, meta.MimeType, opts, logger)
+	if err != nil {
+		if opts.Fallback != videoFallbackBlob || !isVideoServiceError(err) {
+			return nil, err
+		}
+
+		logger.Warn("Video service unavailable, falling back to direct upload", "err", err)
+		blob, err = uploadVideoDirect(pdsURL, accessToken, source, meta.MimeType, logger)
+		if err != nil {
+			return nil, fmt.Errorf("direct video upload failed: %w", err)
+		}
+	}
+
+	captions, err := uploadCaptions(pdsURL, accessToken, captionFiles, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	return &EmbedVideo{
-		Type:  "app.bsky.embed.video",
-		Video: *blob,
-		Alt:   altText,
+		Type:        "app.bsky.embed.video",
+		Video:       *blob,
+		AspectRatio: videoAspectRatio(meta),
+		Alt:         altText,
+		Captions:    captions,
 	}, nil
 }
 
+// validateVideoFallback checks the policy for an unavailable video service.
+func validateVideoFallback(policy string) error {
+	switch policy {
+	case "", videoFallbackNone, videoFallbackBlob, videoFallbackDrop:
+		return nil
+	default:
+		return fmt.Errorf("unknown video fallback policy %q (supported: none, blob, drop)", policy)
+	}
+}
+
 // processVideos processes video file and creates an EmbedVideo structure.
-func processVideos(pdsURL, accessToken, userDID, videoPath, altText string, logger *slog.Logger) (*EmbedVideo, error) {
+func processVideos(pdsURL, accessToken, userDID, videoPath, altText string, opts VideoOptions, logger *slog.Logger) (*EmbedVideo, error) {
 	if videoPath == "" {
 		return nil, nil
 	}
@@ -334,10 +773,15 @@ func processVideos(pdsURL, accessToken, userDID, videoPath, altText string, logg
 		return nil, nil
 	}
 
-	// Default alt text if not provided
-	if altText == "" {
-		altText = "Video"
+	if err := validateVideoFallback(opts.Fallback); err != nil {
+		return nil, err
+	}
+
+	// Default alt text if not provided and allowed by the policy
+	altText, err := applyAltTextPolicy(altText, "Video", path, maxVideoAltTextGraphemes, opts.AltText, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	return processVideo(pdsURL, accessToken, userDID, path, strings.TrimSpace(altText), logger)
+	return processVideo(pdsURL, accessToken, userDID, path, altText, opts, logger)
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
@@ -10,6 +12,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDetectVideoMimeType(t *testing.T) {
@@ -110,7 +113,7 @@ func TestValidateVideoData(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := validateVideoData(tc.path, tc.videoData)
+			err := validateVideoData(tc.path, int64(len(tc.videoData)), maxVideoSize)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("validateVideoData() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -191,7 +194,7 @@ func TestGetServiceAuthToken(t *testing.T) {
 			}))
 			defer mockServer.Close()
 
-			token, err := getServiceAuthToken(mockServer.URL, "test-token", "did:plc:test123", logger)
+			token, err := getServiceAuthToken(mockServer.URL, "test-token", "did:plc:test123", lxmUploadBlob, logger)
 
 			if (err != nil) != tc.wantErr {
 				t.Errorf("getServiceAuthToken() error = %v, wantErr %v", err, tc.wantErr)
@@ -209,17 +212,85 @@ func TestGetServiceAuthToken(t *testing.T) {
 }
 
 func TestGetVideoJobStatus(t *testing.T) {
-	// Note: This test is skipped because getVideoJobStatus uses a hardcoded
-	// videoServiceURL constant that cannot be mocked in tests.
-	// The function is tested indirectly through integration tests.
-	t.Skip("Skipping getVideoJobStatus test - uses hardcoded service URL")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name          string
+		statusCode    int
+		response      string
+		wantState     string
+		wantBlob      bool
+		wantErr       bool
+		wantTransient bool
+	}{
+		{
+			name:       "processing",
+			statusCode: http.StatusOK,
+			response:   `{"jobStatus": {"jobId": "job123", "state": "JOB_STATE_ENCODING", "progress": 40}}`,
+			wantState:  "JOB_STATE_ENCODING",
+		},
+		{
+			name:       "already processed",
+			statusCode: http.StatusConflict,
+			response:   `{"error": "already_exists", "jobStatus": {"jobId": "job123", "blob": {"$type": "blob", "ref": {"$link": "bafkreivideo"}, "mimeType": "video/mp4", "size": 1024}}}`,
+			wantBlob:   true,
+		},
+		{
+			name:          "server error",
+			statusCode:    http.StatusBadGateway,
+			response:      `{"error": "InternalServerError"}`,
+			wantErr:       true,
+			wantTransient: true,
+		},
+		{
+			name:          "rate limited",
+			statusCode:    http.StatusTooManyRequests,
+			response:      `{"error": "RateLimitExceeded"}`,
+			wantErr:       true,
+			wantTransient: true,
+		},
+		{
+			name:       "unknown job",
+			statusCode: http.StatusNotFound,
+			response:   `{"error": "NotFound"}`,
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("jobId") != "job123" || r.Header.Get("Authorization") != "Bearer service-token" {
+					t.Errorf("unexpected job status request %s", r.URL)
+				}
+				w.WriteHeader(tc.statusCode)
+				w.Write([]byte(tc.response))
+			}))
+			defer mockServer.Close()
+			useVideoService(t, mockServer.URL)
+
+			status, err := getVideoJobStatus(context.Background(), "service-token", "job123", logger)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("getVideoJobStatus() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				if got := isTransientJobStatusError(err); got != tc.wantTransient {
+					t.Errorf("isTransientJobStatusError() = %v, want %v", got, tc.wantTransient)
+				}
+				return
+			}
+			if status.State != tc.wantState || (status.Blob != nil) != tc.wantBlob {
+				t.Errorf("getVideoJobStatus() = %+v, want state %q and blob %v", status, tc.wantState, tc.wantBlob)
+			}
+		})
+	}
 }
 
 func TestProcessVideos(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
 	t.Run("empty video path", func(t *testing.T) {
-		result, err := processVideos("https://test.pds", "token", "did:plc:test", "", "", logger)
+		result, err := processVideos("https://test.pds", "token", "did:plc:test", "", "", VideoOptions{}, logger)
 		if err != nil {
 			t.Errorf("processVideos() unexpected error = %v", err)
 		}
@@ -229,7 +300,7 @@ func TestProcessVideos(t *testing.T) {
 	})
 
 	t.Run("whitespace only path", func(t *testing.T) {
-		result, err := processVideos("https://test.pds", "token", "did:plc:test", "   ", "", logger)
+		result, err := processVideos("https://test.pds", "token", "did:plc:test", "   ", "", VideoOptions{}, logger)
 		if err != nil {
 			t.Errorf("processVideos() unexpected error = %v", err)
 		}
@@ -247,7 +318,7 @@ func TestProcessVideos(t *testing.T) {
 		defer os.RemoveAll(tempDir)
 
 		videoPath := filepath.Join(tempDir, "test.mp4")
-		if err := os.WriteFile(videoPath, make([]byte, 1024), 0644); err != nil {
+		if err := os.WriteFile(videoPath, testVideo(30*time.Second), 0644); err != nil {
 			t.Fatalf("Failed to write test video: %v", err)
 		}
 
@@ -280,7 +351,7 @@ func TestProcessVideos(t *testing.T) {
 
 		// Note: This test would need to mock the video service URL properly
 		// For now, it will fail at the upload stage, which is expected
-		result, err := processVideos(authServer.URL, "test-token", "did:plc:test", videoPath, "", logger)
+		result, err := processVideos(authServer.URL, "test-token", "did:plc:test", videoPath, "", VideoOptions{}, logger)
 
 		// We expect an error since we can't properly mock the video service
 		// But we can verify the alt text would be set correctly
@@ -292,43 +363,551 @@ func TestProcessVideos(t *testing.T) {
 	})
 }
 
+// useFastVideoPolling shortens the delays between video job status requests
+// for the duration of a test.
+func useFastVideoPolling(t *testing.T) {
+	t.Helper()
+	pollDelay, maxPollDelay := videoStatusPollDelay, videoStatusMaxPollDelay
+	videoStatusPollDelay, videoStatusMaxPollDelay = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { videoStatusPollDelay, videoStatusMaxPollDelay = pollDelay, maxPollDelay })
+}
+
 func TestPollVideoJobUntilComplete(t *testing.T) {
-	t.Run("immediate completion", func(t *testing.T) {
-		callCount := 0
-		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			callCount++
-			response := map[string]interface{}{
-				"jobStatus": map[string]interface{}{
-					"jobId": "job123",
-					"state": "complete",
-					"blob": map[string]interface{}{
-						"$type": "blob",
-						"ref": map[string]interface{}{
-							"$link": "bafkreimockblob",
+	useFastVideoPolling(t)
+
+	const (
+		processing  = `{"jobStatus": {"jobId": "job123", "state": "JOB_STATE_ENCODING", "progress": 50}}`
+		complete    = `{"jobStatus": {"jobId": "job123", "state": "JOB_STATE_COMPLETED", "blob": {"$type": "blob", "ref": {"$link": "bafkreivideo"}, "mimeType": "video/mp4", "size": 1024}}}`
+		failed      = `{"jobStatus": {"jobId": "job123", "state": "failed", "error": "unsupported codec"}}`
+		unavailable = ""
+	)
+
+	tests := []struct {
+		name      string
+		responses []string // Responses in order; the last one repeats.
+		maxWait   time.Duration
+		wantErr   string
+		wantCalls int
+	}{
+		{
+			name:      "completes after processing",
+			responses: []string{processing, processing, complete},
+			wantCalls: 3,
+		},
+		{
+			name:      "tolerates transient errors",
+			responses: []string{processing, unavailable, unavailable, unavailable, processing, unavailable, complete},
+			wantCalls: 7,
+		},
+		{
+			name:      "too many consecutive transient errors",
+			responses: []string{processing, unavailable},
+			wantErr:   "status code: 503",
+			wantCalls: 2 + videoStatusMaxErrors,
+		},
+		{
+			name:      "processing failed",
+			responses: []string{processing, failed},
+			wantErr:   "unsupported codec",
+			wantCalls: 2,
+		},
+		{
+			name:      "timed out",
+			responses: []string{processing},
+			maxWait:   50 * time.Millisecond,
+			wantErr:   "timed out after 50ms",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+			calls := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				response := tc.responses[min(calls, len(tc.responses)-1)]
+				calls++
+				if response == unavailable {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(response))
+			}))
+			defer mockServer.Close()
+			useVideoService(t, mockServer.URL)
+
+			ctx, cancel := videoProcessingContext(VideoOptions{MaxWait: tc.maxWait})
+			defer cancel()
+
+			blob, err := pollVideoJobUntilComplete(ctx, "service-token", "job123", logger)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("pollVideoJobUntilComplete() error = %v, want error containing %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("pollVideoJobUntilComplete() unexpected error = %v", err)
+			} else if blob.Ref.Link != "bafkreivideo" {
+				t.Errorf("pollVideoJobUntilComplete() blob = %s, want bafkreivideo", blob.Ref.Link)
+			}
+
+			if tc.wantCalls > 0 && calls != tc.wantCalls {
+				t.Errorf("job status requested %d times, want %d", calls, tc.wantCalls)
+			}
+			if !strings.Contains(buf.String(), "percent=50") {
+				t.Errorf("processing progress not logged at info level: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestOpenVideoSourceLocal(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "bluesky-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	videoPath := filepath.Join(tempDir, "demo.webm")
+	if err := os.WriteFile(videoPath, make([]byte, 4096), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+
+	source, err := openVideoSource(videoPath, maxVideoSize, VideoOptions{}, logger)
+	if err != nil {
+		t.Fatalf("openVideoSource() unexpected error = %v", err)
+	}
+	if source.filename != "demo.webm" || source.size != 4096 {
+		t.Errorf("openVideoSource() = %s (%d bytes), want demo.webm (4096 bytes)", source.filename, source.size)
+	}
+	if err := source.Close(); err != nil {
+		t.Errorf("Close() unexpected error = %v", err)
+	}
+	if _, err := os.Stat(videoPath); err != nil {
+		t.Errorf("Close() removed local video file: %v", err)
+	}
+
+	if _, err := openVideoSource(filepath.Join(tempDir, "missing.mp4"), maxVideoSize, VideoOptions{}, logger); err == nil {
+		t.Error("openVideoSource() expected error for missing file, got nil")
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	data := make([]byte, 1000)
+	reader := &progressReader{
+		reader: bytes.NewReader(data),
+		total:  int64(len(data)),
+		logger: logger,
+	}
+
+	// Read in small chunks so every progress step is crossed.
+	buf := make([]byte, 10)
+	var read int
+	for {
+		n, err := reader.Read(buf)
+		read += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read() unexpected error = %v", err)
+		}
+	}
+
+	if read != len(data) {
+		t.Errorf("Read() returned %d bytes, want %d", read, len(data))
+	}
+	if got := strings.Count(logs.String(), "Video upload progress"); got != 5 {
+		t.Errorf("progress messages = %d, want 5 (0, 25, 50, 75, 100 percent)\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "percent=100") {
+		t.Errorf("progress messages missing completion:\n%s", logs.String())
+	}
+}
+
+// useVideoService points the video service at a test server for the duration of a test.
+func useVideoService(t *testing.T, serviceURL string) {
+	t.Helper()
+	original := videoServiceURL
+	videoServiceURL = serviceURL
+	t.Cleanup(func() { videoServiceURL = original })
+}
+
+func TestProcessVideoUploadLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "video-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	video := testVideo(30 * time.Second)
+	videoPath := filepath.Join(tempDir, "demo.mp4")
+	if err := os.WriteFile(videoPath, video, 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		limitsStatus int
+		limits       string
+		wantErr      string
+		wantUploaded bool
+	}{
+		{
+			name:         "upload allowed",
+			limitsStatus: http.StatusOK,
+			limits:       `{"canUpload": true, "remainingDailyVideos": 24, "remainingDailyBytes": 10000000}`,
+			wantUploaded: true,
+		},
+		{
+			name:         "email not verified",
+			limitsStatus: http.StatusOK,
+			limits:       `{"canUpload": false, "message": "Please verify your email"}`,
+			wantErr:      "Please verify your email",
+		},
+		{
+			name:         "daily quota exhausted",
+			limitsStatus: http.StatusOK,
+			limits:       `{"canUpload": false, "remainingDailyVideos": 0, "error": "daily_limit"}`,
+			wantErr:      "daily_limit",
+		},
+		{
+			name:         "video larger than remaining bytes",
+			limitsStatus: http.StatusOK,
+			limits:       `{"canUpload": true, "remainingDailyVideos": 3, "remainingDailyBytes": 100}`,
+			wantErr:      "exceeds the remaining daily video upload quota of 100 bytes",
+		},
+		{
+			name:         "remaining bytes above the size limit",
+			limitsStatus: http.StatusOK,
+			limits:       `{"canUpload": true, "remainingDailyVideos": 24, "remainingDailyBytes": 10737418240}`,
+			wantUploaded: true,
+		},
+		{
+			name:         "limits unavailable",
+			limitsStatus: http.StatusNotFound,
+			limits:       `{"error": "MethodNotImplemented"}`,
+			wantUploaded: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var lxms []string
+			pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var reqBody struct {
+					Lxm string `json:"lxm"`
+				}
+				json.NewDecoder(r.Body).Decode(&reqBody)
+				lxms = append(lxms, reqBody.Lxm)
+				json.NewEncoder(w).Encode(ServiceAuthResponse{Token: "token-for-" + reqBody.Lxm})
+			}))
+			defer pdsServer.Close()
+
+			uploaded := false
+			videoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "getUploadLimits"):
+					if r.Header.Get("Authorization") != "Bearer token-for-"+lxmGetUploadLimits {
+						t.Errorf("getUploadLimits Authorization = %s", r.Header.Get("Authorization"))
+					}
+					w.WriteHeader(tc.limitsStatus)
+					w.Write([]byte(tc.limits))
+				case strings.Contains(r.URL.Path, "uploadVideo"):
+					uploaded = true
+					if r.ContentLength != int64(len(video)) {
+						t.Errorf("uploadVideo Content-Length = %d, want %d", r.ContentLength, len(video))
+					}
+					json.NewEncoder(w).Encode(VideoUploadResponse{
+						JobID: "job123",
+						Status: &VideoJobStatus{
+							Blob: &Blob{Type: "blob", Ref: BlobRef{Link: "bafkreivideo"}, MimeType: "video/mp4", Size: len(video)},
 						},
-						"mimeType": "video/mp4",
-						"size":     1024000,
-					},
-				},
+					})
+				}
+			}))
+			defer videoServer.Close()
+			useVideoService(t, videoServer.URL)
+
+			result, err := processVideo(pdsServer.URL, "access-token", "did:plc:test", videoPath, "Demo", VideoOptions{}, logger)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("processVideo() error = %v, want error containing %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("processVideo() unexpected error = %v", err)
+			} else if result.Video.Ref.Link != "bafkreivideo" {
+				t.Errorf("processVideo() blob = %s, want bafkreivideo", result.Video.Ref.Link)
 			}
-			json.NewEncoder(w).Encode(response)
-		}))
-		defer mockServer.Close()
 
-		// This test would need proper mocking of the video service
-		// Skipping actual execution as it requires more complex setup
-		_ = callCount
-	})
+			if uploaded != tc.wantUploaded {
+				t.Errorf("video uploaded = %v, want %v", uploaded, tc.wantUploaded)
+			}
+			if len(lxms) == 0 || lxms[0] != lxmGetUploadLimits {
+				t.Errorf("service auth scopes = %v, want %s first", lxms, lxmGetUploadLimits)
+			}
+		})
+	}
+}
+
+func TestProcessVideoValidatesBeforeServiceAuth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tempDir := t.TempDir()
+
+	corruptPath := filepath.Join(tempDir, "corrupt.mp4")
+	if err := os.WriteFile(corruptPath, make([]byte, 4096), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+	longPath := filepath.Join(tempDir, "long.mp4")
+	if err := os.WriteFile(longPath, testVideo(10*time.Minute), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
 
-	t.Run("timeout", func(t *testing.T) {
-		// Save original timeout
-		originalTimeout := videoStatusMaxWait
-		defer func() {
-			// Can't reassign const in production, but this shows intent
-			_ = originalTimeout
-		}()
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "corrupt video", path: corruptPath, wantErr: "not a valid MP4, MOV, or WebM file"},
+		{name: "video too long", path: longPath, wantErr: "exceeds maximum duration"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			useVideoService(t, server.URL)
+
+			_, err := processVideo(server.URL, "access-token", "did:plc:test", tc.path, "Demo", VideoOptions{}, logger)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("processVideo() error = %v, want error containing %q", err, tc.wantErr)
+			}
+			if requests != 0 {
+				t.Errorf("processVideo() sent %d requests before rejecting the video, want 0", requests)
+			}
+		})
+	}
+}
+
+func TestCheckVideoUploadLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ServiceAuthResponse{Token: "service-token"})
+	}))
+	defer pdsServer.Close()
+
+	tests := []struct {
+		name   string
+		limits string
+		want   int64
+	}{
+		{name: "daily quota of gigabytes", limits: `{"canUpload": true, "remainingDailyBytes": 10737418240}`, want: maxVideoSize},
+		{name: "daily quota below the size limit", limits: `{"canUpload": true, "remainingDailyBytes": 1000000}`, want: 1000000},
+		{name: "daily quota not reported", limits: `{"canUpload": true}`, want: maxVideoSize},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			videoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tc.limits))
+			}))
+			defer videoServer.Close()
+			useVideoService(t, videoServer.URL)
+
+			got, err := checkVideoUploadLimits(pdsServer.URL, "access-token", "did:plc:test", logger)
+			if err != nil {
+				t.Fatalf("checkVideoUploadLimits() unexpected error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("checkVideoUploadLimits() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
 
-		// Test would verify timeout behavior
-		// Skipped due to time constraints in testing
+func TestProcessVideoFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "video-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	video := testVideo(30 * time.Second)
+	videoPath := filepath.Join(tempDir, "demo.mp4")
+	if err := os.WriteFile(videoPath, video, 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		fallback        string
+		authFails       bool
+		uploadStatus    int
+		uploadResponse  string
+		wantErr         string
+		wantServiceErr  bool
+		wantDirectBlob  bool
+		wantServiceBlob bool
+	}{
+		{
+			name:            "service available",
+			fallback:        videoFallbackBlob,
+			uploadStatus:    http.StatusOK,
+			uploadResponse:  `{"jobId": "job123", "jobStatus": {"blob": {"$type": "blob", "ref": {"$link": "bafkreiservice"}, "mimeType": "video/mp4", "size": 1024}}}`,
+			wantServiceBlob: true,
+		},
+		{
+			name:           "service unavailable without fallback",
+			fallback:       videoFallbackNone,
+			uploadStatus:   http.StatusServiceUnavailable,
+			wantErr:        "failed to upload video",
+			wantServiceErr: true,
+		},
+		{
+			name:           "service unavailable with blob fallback",
+			fallback:       videoFallbackBlob,
+			uploadStatus:   http.StatusServiceUnavailable,
+			wantDirectBlob: true,
+		},
+		{
+			name:           "service auth fails with blob fallback",
+			fallback:       videoFallbackBlob,
+			authFails:      true,
+			wantDirectBlob: true,
+		},
+		{
+			name:           "processing failed is not a service outage",
+			fallback:       videoFallbackBlob,
+			uploadStatus:   http.StatusOK,
+			uploadResponse: `{"jobId": "job123"}`,
+			wantErr:        "unsupported codec",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFastVideoPolling(t)
+
+			var directUpload []byte
+			pdsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "getServiceAuth"):
+					if tc.authFails {
+						w.WriteHeader(http.StatusUnauthorized)
+						w.Write([]byte(`{"error": "AuthRequired"}`))
+						return
+					}
+					json.NewEncoder(w).Encode(ServiceAuthResponse{Token: "service-token"})
+				case strings.Contains(r.URL.Path, "uploadBlob"):
+					directUpload, _ = io.ReadAll(r.Body)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"blob": Blob{Type: "blob", Ref: BlobRef{Link: "bafkreidirect"}, MimeType: r.Header.Get("Content-Type"), Size: len(directUpload)},
+					})
+				}
+			}))
+			defer pdsServer.Close()
+
+			videoServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.Contains(r.URL.Path, "getUploadLimits"):
+					http.NotFound(w, r)
+				case strings.Contains(r.URL.Path, "uploadVideo"):
+					io.Copy(io.Discard, r.Body)
+					w.WriteHeader(tc.uploadStatus)
+					w.Write([]byte(tc.uploadResponse))
+				case strings.Contains(r.URL.Path, "getJobStatus"):
+					w.Write([]byte(`{"jobStatus": {"jobId": "job123", "state": "failed", "error": "unsupported codec"}}`))
+				}
+			}))
+			defer videoServer.Close()
+			useVideoService(t, videoServer.URL)
+
+			result, err := processVideo(pdsServer.URL, "access-token", "did:plc:test", videoPath, "Demo", VideoOptions{Fallback: tc.fallback}, logger)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("processVideo() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				if got := isVideoServiceError(err); got != tc.wantServiceErr {
+					t.Errorf("isVideoServiceError() = %v, want %v", got, tc.wantServiceErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processVideo() unexpected error = %v", err)
+			}
+
+			switch {
+			case tc.wantDirectBlob:
+				if result.Video.Ref.Link != "bafkreidirect" || result.Video.MimeType != "video/mp4" {
+					t.Errorf("processVideo() blob = %+v, want direct upload", result.Video)
+				}
+				if !bytes.Equal(directUpload, video) {
+					t.Errorf("direct upload sent %d bytes, want the whole %d byte video", len(directUpload), len(video))
+				}
+			case tc.wantServiceBlob:
+				if result.Video.Ref.Link != "bafkreiservice" || directUpload != nil {
+					t.Errorf("processVideo() blob = %s, want video service blob only", result.Video.Ref.Link)
+				}
+			}
+			if ar := result.AspectRatio; ar == nil || ar.Width != 1280 || ar.Height != 720 {
+				t.Errorf("processVideo() aspectRatio = %v, want 1280x720", ar)
+			}
+		})
+	}
+
+	t.Run("video too large for direct upload", func(t *testing.T) {
+		source := &videoSource{filename: "large.mp4", size: maxDirectVideoSize + 1}
+		if _, err := uploadVideoDirect("https://test.pds", "token", source, "video/mp4", logger); err == nil {
+			t.Error("uploadVideoDirect() expected error for video over the direct upload limit, got nil")
+		}
+	})
+
+	t.Run("unknown fallback policy", func(t *testing.T) {
+		if _, err := processVideos("https://test.pds", "token", "did:plc:test", videoPath, "Demo", VideoOptions{Fallback: "retry"}, logger); err == nil {
+			t.Error("processVideos() expected error for unknown fallback policy, got nil")
+		}
 	})
 }
+
+func TestProcessVideosAltTextLength(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	videoPath := filepath.Join(t.TempDir(), "demo.mp4")
+	if err := os.WriteFile(videoPath, testVideo(30*time.Second), 0644); err != nil {
+		t.Fatalf("Failed to write test video: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	useVideoService(t, server.URL)
+
+	// Valid as image alt text, but over the video limit.
+	alt := strings.Repeat("a", 1500)
+	opts := VideoOptions{AltText: AltTextPolicy{Mode: altTextPolicyRequire, CheckLength: true}}
+
+	_, err := processVideos(server.URL, "access-token", "did:plc:test", videoPath, alt, opts, logger)
+	if err == nil || !strings.Contains(err.Error(), "maximum is 1000") {
+		t.Fatalf("processVideos() error = %v, want video alt text length error", err)
+	}
+	if requests != 0 {
+		t.Errorf("processVideos() sent %d requests before rejecting the alt text, want 0", requests)
+	}
+}
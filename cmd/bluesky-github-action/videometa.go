@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Limits for reading video container headers.
+const (
+	maxVideoHeaderSize = 16 * 1024 * 1024 // Largest moov box or WebM Tracks element read into memory.
+	ebmlHeaderReadSize = 12               // Longest EBML element ID (4) plus size (8).
+	maxEBMLHeaderSize  = 4096             // Largest EBML header read into memory.
+)
+
+// EBML element IDs used to read WebM and Matroska headers.
+const (
+	ebmlIDHeader        = 0x1A45DFA3
+	ebmlIDDocType       = 0x4282
+	ebmlIDSegment       = 0x18538067
+	ebmlIDInfo          = 0x1549A966
+	ebmlIDTimecodeScale = 0x2AD7B1
+	ebmlIDDuration      = 0x4489
+	ebmlIDCluster       = 0x1F43B675
+	ebmlIDTracks        = 0x1654AE6B
+	ebmlIDTrackEntry    = 0xAE
+	ebmlIDTrackType     = 0x83
+	ebmlIDVideo         = 0xE0
+	ebmlIDPixelWidth    = 0xB0
+	ebmlIDPixelHeight   = 0xBA
+	ebmlIDDisplayWidth  = 0x54B0
+	ebmlIDDisplayHeight = 0x54BA
+	ebmlIDDisplayUnit   = 0x54B2
+)
+
+// Values of container header fields.
+const (
+	ebmlTrackTypeVideo       = 1       // TrackType of video tracks.
+	ebmlDefaultTimecodeScale = 1000000 // Nanoseconds per timestamp tick when TimecodeScale is missing.
+	quickTimeBrand           = "qt  "  // Major brand of QuickTime movies.
+)
+
+// quickTimeAtoms lists top-level atoms that legacy QuickTime movies without an
+// ftyp box start with.
+var quickTimeAtoms = map[string]bool{
+	"moov": true,
+	"mdat": true,
+	"wide": true,
+	"free": true,
+	"skip": true,
+}
+
+// VideoMetadata describes a video read from its container headers.
+type VideoMetadata struct {
+	MimeType string        // Format detected from the content.
+	Duration time.Duration // Zero if the container does not declare it.
+	Width    int           // Display width, after rotation. Zero if unknown.
+	Height   int           // Display height, after rotation. Zero if unknown.
+}
+
+// probeVideo reads the format, duration, and display size of an MP4, MOV, or
+// WebM video from its container headers without loading the whole file.
+func probeVideo(r io.ReaderAt, size int64) (*VideoMetadata, error) {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read video header: %w", err)
+	}
+
+	switch {
+	case binary.BigEndian.Uint32(header[0:4]) == ebmlIDHeader:
+		return probeWebM(r, size)
+	case string(header[4:8]) == "ftyp":
+		mimeType := "video/mp4"
+		if string(header[8:12]) == quickTimeBrand {
+			mimeType = "video/quicktime"
+		}
+		return probeMP4(r, size, mimeType)
+	case quickTimeAtoms[string(header[4:8])]:
+		return probeMP4(r, size, "video/quicktime")
+	default:
+		return nil, fmt.Errorf("unrecognized video container")
+	}
+}
+
+// probeMP4 locates the moov box among the top-level boxes of an MP4 or MOV
+// file, which may come before or after the media data.
+func probeMP4(r io.ReaderAt, size int64, mimeType string) (*VideoMetadata, error) {
+	header := make([]byte, 16)
+
+	for offset := int64(0); offset+8 <= size; {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, fmt.Errorf("failed to read box header: %w", err)
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+
+		switch boxSize {
+		case 0:
+			boxSize = size - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, fmt.Errorf("failed to read box header: %w", err)
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+
+		if boxSize < headerSize || boxSize > size-offset {
+			return nil, fmt.Errorf("invalid %q box size at offset %d", boxType, offset)
+		}
+
+		if boxType == "moov" {
+			if boxSize-headerSize > maxVideoHeaderSize {
+				return nil, fmt.Errorf("moov box too large (%d bytes)", boxSize)
+			}
+			moov := make([]byte, boxSize-headerSize)
+			if _, err := r.ReadAt(moov, offset+headerSize); err != nil {
+				return nil, fmt.Errorf("failed to read moov box: %w", err)
+			}
+			return parseMoov(moov, mimeType)
+		}
+
+		offset += boxSize
+	}
+
+	return nil, fmt.Errorf("no moov box found")
+}
+
+// parseMoov reads the duration of a moov box and the display size of its
+// first video track.
+func parseMoov(moov []byte, mimeType string) (*VideoMetadata, error) {
+	var meta *VideoMetadata
+
+	forEachBox(moov, func(boxType string, trak []byte) bool {
+		if boxType != "trak" {
+			return true
+		}
+
+		hdlr := findBox(findBox(trak, "mdia"), "hdlr")
+		// Full box header (4), pre_defined (4), then the handler type.
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			return true
+		}
+
+		width, height := tkhdDimensions(findBox(trak, "tkhd"))
+		meta = &VideoMetadata{MimeType: mimeType, Width: width, Height: height}
+		return false
+	})
+
+	if meta == nil {
+		return nil, fmt.Errorf("no video track found")
+	}
+	meta.Duration = mvhdDuration(findBox(moov, "mvhd"))
+	return meta, nil
+}
+
+// mvhdDuration reads the duration of a movie header box. It returns zero if
+// the box is missing or declares an unknown duration.
+func mvhdDuration(mvhd []byte) time.Duration {
+	var timescale, duration uint64
+
+	switch {
+	case len(mvhd) >= 32 && mvhd[0] == 1:
+		// Version 1 uses 64-bit creation time, modification time, and duration.
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[20:24]))
+		duration = binary.BigEndian.Uint64(mvhd[24:32])
+		if duration == math.MaxUint64 {
+			return 0
+		}
+	case len(mvhd) >= 20 && mvhd[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(mvhd[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:20]))
+		if duration == math.MaxUint32 {
+			return 0
+		}
+	}
+
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// tkhdDimensions reads the presentation size from a track header box and
+// swaps width and height when the transformation matrix rotates by 90 or 270
+// degrees. It returns zero dimensions if the box is missing or truncated.
+func tkhdDimensions(tkhd []byte) (int, int) {
+	if len(tkhd) < 4 {
+		return 0, 0
+	}
+
+	// Version 1 uses 64-bit creation time, modification time, and duration.
+	matrixOffset := 40
+	if tkhd[0] == 1 {
+		matrixOffset = 52
+	}
+	if len(tkhd) < matrixOffset+44 {
+		return 0, 0
+	}
+
+	matrix := tkhd[matrixOffset : matrixOffset+36]
+	a := int32(binary.BigEndian.Uint32(matrix[0:4]))
+	b := int32(binary.BigEndian.Uint32(matrix[4:8]))
+	c := int32(binary.BigEndian.Uint32(matrix[12:16]))
+
+	// Width and height are 16.16 fixed-point numbers.
+	width := int(binary.BigEndian.Uint32(tkhd[matrixOffset+36:]) >> 16)
+	height := int(binary.BigEndian.Uint32(tkhd[matrixOffset+40:]) >> 16)
+	if a == 0 && b != 0 && c != 0 {
+		width, height = height, width
+	}
+
+	return width, height
+}
+
+// parseEBMLVint decodes an EBML variable-length integer and returns its value,
+// its length in bytes, and whether all value bits are set (unknown size).
+func parseEBMLVint(data []byte, keepMarker bool) (uint64, int, bool, bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return 0, 0, false, false
+	}
+
+	length := 1
+	for mask := byte(0x80); data[0]&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 8 || len(data) < length {
+		return 0, 0, false, false
+	}
+
+	value := uint64(data[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	allOnes := value == uint64(0xFF>>length)
+	for _, b := range data[1:length] {
+		value = value<<8 | uint64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+
+	return value, length, allOnes, true
+}
+
+// parseEBMLElementHeader decodes an element ID and data size. The size is -1
+// for elements of unknown size.
+func parseEBMLElementHeader(data []byte) (uint32, int, int64, error) {
+	id, idLength, _, ok := parseEBMLVint(data, true)
+	if !ok || idLength > 4 {
+		return 0, 0, 0, fmt.Errorf("invalid EBML element ID")
+	}
+
+	size, sizeLength, unknown, ok := parseEBMLVint(data[idLength:], false)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("invalid EBML element size")
+	}
+	if unknown {
+		return uint32(id), idLength + sizeLength, -1, nil
+	}
+
+	return uint32(id), idLength + sizeLength, int64(size), nil
+}
+
+// forEachEBMLElement calls fn for each element in data until fn returns false.
+func forEachEBMLElement(data []byte, fn func(id uint32, payload []byte) bool) {
+	for offset := 0; offset < len(data); {
+		id, headerLength, size, err := parseEBMLElementHeader(data[offset:])
+		if err != nil || size < 0 || size > int64(len(data)-offset-headerLength) {
+			return
+		}
+
+		start := offset + headerLength
+		if !fn(id, data[start:start+int(size)]) {
+			return
+		}
+		offset = start + int(size)
+	}
+}
+
+// ebmlUint decodes an unsigned integer element payload.
+func ebmlUint(payload []byte) uint64 {
+	var value uint64
+	for _, b := range payload {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// ebmlFloat decodes a 4 or 8 byte floating-point element payload.
+func ebmlFloat(payload []byte) float64 {
+	switch len(payload) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(payload)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(payload))
+	default:
+		return 0
+	}
+}
+
+// readEBMLElementHeader reads the element header at offset of r.
+func readEBMLElementHeader(r io.ReaderAt, offset int64) (uint32, int, int64, error) {
+	buf := make([]byte, ebmlHeaderReadSize)
+	n, err := r.ReadAt(buf, offset)
+	if n == 0 && err != nil {
+		return 0, 0, 0, err
+	}
+	return parseEBMLElementHeader(buf[:n])
+}
+
+// readEBMLPayload reads the payload of an element of known size into memory.
+func readEBMLPayload(r io.ReaderAt, offset, size, maxSize int64, name string) ([]byte, error) {
+	if size > maxSize {
+		return nil, fmt.Errorf("%s element too large (%d bytes)", name, size)
+	}
+	payload := make([]byte, size)
+	if _, err := r.ReadAt(payload, offset); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return payload, nil
+}
+
+// webmMimeType returns the MIME type of the document type declared in an EBML
+// header.
+func webmMimeType(header []byte) string {
+	docType := "matroska" // Default DocType of the EBML specification.
+	forEachEBMLElement(header, func(id uint32, payload []byte) bool {
+		if id == ebmlIDDocType {
+			docType = string(bytes.TrimRight(payload, "\x00"))
+			return false
+		}
+		return true
+	})
+
+	if docType == "webm" {
+		return "video/webm"
+	}
+	return "video/x-" + docType
+}
+
+// readWebMSegment reads the EBML header and returns its payload together with
+// the offsets of the first and past the last child element of the segment.
+func readWebMSegment(r io.ReaderAt, size int64) ([]byte, int64, int64, error) {
+	_, headerLength, headerSize, err := readEBMLElementHeader(r, 0)
+	if err != nil || headerSize < 0 {
+		return nil, 0, 0, fmt.Errorf("invalid EBML header")
+	}
+	header, err := readEBMLPayload(r, int64(headerLength), headerSize, maxEBMLHeaderSize, "EBML header")
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	offset := int64(headerLength) + headerSize
+	id, segmentHeaderLength, segmentSize, err := readEBMLElementHeader(r, offset)
+	if err != nil || id != ebmlIDSegment {
+		return nil, 0, 0, fmt.Errorf("no WebM segment found")
+	}
+
+	offset += int64(segmentHeaderLength)
+	end := size
+	if segmentSize >= 0 && offset+segmentSize < end {
+		end = offset + segmentSize
+	}
+
+	return header, offset, end, nil
+}
+
+// probeWebM walks the top-level elements of a WebM segment until it has read
+// the Info and Tracks elements, which precede the clusters holding the media
+// data.
+func probeWebM(r io.ReaderAt, size int64) (*VideoMetadata, error) {
+	header, offset, end, err := readWebMSegment(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *VideoMetadata
+	var duration time.Duration
+
+	for offset < end && (meta == nil || duration == 0) {
+		id, headerLength, elementSize, err := readEBMLElementHeader(r, offset)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		offset += int64(headerLength)
+
+		// Clusters, which may have unknown size and cannot be skipped, follow the headers.
+		if elementSize < 0 || id == ebmlIDCluster {
+			break
+		}
+
+		switch id {
+		case ebmlIDInfo:
+			info, err := readEBMLPayload(r, offset, elementSize, maxVideoHeaderSize, "info")
+			if err != nil {
+				return nil, err
+			}
+			duration = parseWebMDuration(info)
+		case ebmlIDTracks:
+			tracks, err := readEBMLPayload(r, offset, elementSize, maxVideoHeaderSize, "tracks")
+			if err != nil {
+				return nil, err
+			}
+			if meta, err = parseWebMTracks(tracks); err != nil {
+				return nil, err
+			}
+		}
+
+		offset += elementSize
+	}
+
+	if meta == nil {
+		return nil, fmt.Errorf("no WebM tracks found")
+	}
+	meta.MimeType = webmMimeType(header)
+	meta.Duration = duration
+	return meta, nil
+}
+
+// parseWebMDuration reads the segment duration from an Info element. It
+// returns zero if the duration is missing, as in live recordings.
+func parseWebMDuration(info []byte) time.Duration {
+	timecodeScale := uint64(ebmlDefaultTimecodeScale)
+	var duration float64
+
+	forEachEBMLElement(info, func(id uint32, payload []byte) bool {
+		switch id {
+		case ebmlIDTimecodeScale:
+			timecodeScale = ebmlUint(payload)
+		case ebmlIDDuration:
+			duration = ebmlFloat(payload)
+		}
+		return true
+	})
+
+	if duration <= 0 || math.IsNaN(duration) || math.IsInf(duration, 0) {
+		return 0
+	}
+	// Duration is a floating-point number of ticks of TimecodeScale nanoseconds.
+	return time.Duration(duration * float64(timecodeScale))
+}
+
+// parseWebMTracks reads the display size of the first video track, preferring
+// the display size over the pixel size when it is given in pixels.
+func parseWebMTracks(tracks []byte) (*VideoMetadata, error) {
+	var meta *VideoMetadata
+
+	forEachEBMLElement(tracks, func(id uint32, entry []byte) bool {
+		if id != ebmlIDTrackEntry {
+			return true
+		}
+
+		var trackType uint64
+		var video []byte
+		forEachEBMLElement(entry, func(id uint32, payload []byte) bool {
+			switch id {
+			case ebmlIDTrackType:
+				trackType = ebmlUint(payload)
+			case ebmlIDVideo:
+				video = payload
+			}
+			return true
+		})
+		if trackType != ebmlTrackTypeVideo || video == nil {
+			return true
+		}
+
+		var pixelWidth, pixelHeight, displayWidth, displayHeight, displayUnit uint64
+		forEachEBMLElement(video, func(id uint32, payload []byte) bool {
+			switch id {
+			case ebmlIDPixelWidth:
+				pixelWidth = ebmlUint(payload)
+			case ebmlIDPixelHeight:
+				pixelHeight = ebmlUint(payload)
+			case ebmlIDDisplayWidth:
+				displayWidth = ebmlUint(payload)
+			case ebmlIDDisplayHeight:
+				displayHeight = ebmlUint(payload)
+			case ebmlIDDisplayUnit:
+				displayUnit = ebmlUint(payload)
+			}
+			return true
+		})
+
+		width, height := pixelWidth, pixelHeight
+		if displayUnit == 0 && displayWidth > 0 && displayHeight > 0 {
+			width, height = displayWidth, displayHeight
+		}
+
+		meta = &VideoMetadata{Width: int(width), Height: int(height)}
+		return false
+	})
+
+	if meta == nil {
+		return nil, fmt.Errorf("no video track found")
+	}
+	return meta, nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Transformation matrices of the tkhd box, as 16.16 and 2.30 fixed-point values.
+var (
+	identityMatrix = [9]int32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	rotate90Matrix = [9]int32{0, 0x10000, 0, -0x10000, 0, 0, 0, 0, 0x40000000}
+)
+
+// testTkhd builds a version 0 or 1 track header box payload.
+func testTkhd(version byte, width, height int, matrix [9]int32) []byte {
+	tkhd := []byte{version, 0, 0, 0x03}
+	if version == 1 {
+		tkhd = append(tkhd, make([]byte, 32)...)
+	} else {
+		tkhd = append(tkhd, make([]byte, 20)...)
+	}
+	tkhd = append(tkhd, make([]byte, 16)...)
+	for _, value := range matrix {
+		tkhd = binary.BigEndian.AppendUint32(tkhd, uint32(value))
+	}
+	tkhd = binary.BigEndian.AppendUint32(tkhd, uint32(width)<<16)
+	return binary.BigEndian.AppendUint32(tkhd, uint32(height)<<16)
+}
+
+// testMvhd builds a version 0 or 1 movie header box payload.
+func testMvhd(version byte, timescale uint32, duration uint64) []byte {
+	mvhd := []byte{version, 0, 0, 0}
+	if version == 1 {
+		mvhd = append(mvhd, make([]byte, 16)...)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, timescale)
+		mvhd = binary.BigEndian.AppendUint64(mvhd, duration)
+	} else {
+		mvhd = append(mvhd, make([]byte, 8)...)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, timescale)
+		mvhd = binary.BigEndian.AppendUint32(mvhd, uint32(duration))
+	}
+	return append(mvhd, make([]byte, 80)...)
+}
+
+// testTrak builds a track box with the given handler type and track header.
+func testTrak(handler string, tkhd []byte) []byte {
+	hdlr := append(make([]byte, 8), []byte(handler)...)
+	hdlr = append(hdlr, make([]byte, 12)...)
+	return testBox("trak", testBox("tkhd", tkhd), testBox("mdia", testBox("hdlr", hdlr)))
+}
+
+// testMP4 builds an MP4 file with an audio and a video track, placing the moov
+// box before or after the media data.
+func testMP4(brand string, mvhd, videoTkhd []byte, moovAtEnd bool) []byte {
+	ftyp := testBox("ftyp", []byte(brand), make([]byte, 4), []byte("isom"))
+	tracks := [][]byte{testBox("mvhd", mvhd), testTrak("soun", testTkhd(0, 0, 0, identityMatrix))}
+	if videoTkhd != nil {
+		tracks = append(tracks, testTrak("vide", videoTkhd))
+	}
+	moov := testBox("moov", tracks...)
+	mdat := testBox("mdat", make([]byte, 512))
+
+	if moovAtEnd {
+		return bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+	}
+	return bytes.Join([][]byte{ftyp, moov, mdat}, nil)
+}
+
+// testVideo returns a 1280x720 MP4 video of the given duration.
+func testVideo(duration time.Duration) []byte {
+	mvhd := testMvhd(0, 1000, uint64(duration.Milliseconds()))
+	return testMP4("isom", mvhd, testTkhd(0, 1280, 720, identityMatrix), false)
+}
+
+// ebmlElement encodes an EBML element with an 8-byte data size.
+func ebmlElement(id uint32, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	var element []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(element) > 0 {
+			element = append(element, b)
+		}
+	}
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	element = append(element, size...)
+	return append(element, body...)
+}
+
+// ebmlUintElement encodes an unsigned integer EBML element.
+func ebmlUintElement(id uint32, value uint64) []byte {
+	return ebmlElement(id, binary.BigEndian.AppendUint64(nil, value))
+}
+
+// testWebM builds a WebM file of the given document type with an audio track
+// and a video track. A zero duration is left out of the segment info.
+func testWebM(docType string, duration float64, pixelWidth, pixelHeight, displayWidth, displayHeight uint64) []byte {
+	videoSettings := [][]byte{
+		ebmlUintElement(ebmlIDPixelWidth, pixelWidth),
+		ebmlUintElement(ebmlIDPixelHeight, pixelHeight),
+	}
+	if displayWidth > 0 {
+		videoSettings = append(videoSettings,
+			ebmlUintElement(ebmlIDDisplayWidth, displayWidth),
+			ebmlUintElement(ebmlIDDisplayHeight, displayHeight),
+		)
+	}
+
+	info := [][]byte{ebmlUintElement(ebmlIDTimecodeScale, 1000000)}
+	if duration > 0 {
+		info = append(info, ebmlElement(ebmlIDDuration, binary.BigEndian.AppendUint64(nil, math.Float64bits(duration))))
+	}
+
+	header := ebmlElement(ebmlIDHeader, ebmlElement(ebmlIDDocType, []byte(docType)))
+	segment := ebmlElement(ebmlIDSegment,
+		ebmlElement(ebmlIDInfo, info...),
+		ebmlElement(ebmlIDTracks,
+			ebmlElement(ebmlIDTrackEntry, ebmlUintElement(ebmlIDTrackType, 2)),
+			ebmlElement(ebmlIDTrackEntry,
+				ebmlUintElement(ebmlIDTrackType, ebmlTrackTypeVideo),
+				ebmlElement(ebmlIDVideo, videoSettings...),
+			),
+		),
+		ebmlElement(ebmlIDCluster, make([]byte, 256)),
+	)
+	return append(header, segment...)
+}
+
+func TestProbeVideo(t *testing.T) {
+	mvhd := testMvhd(0, 600, 27000)
+
+	tests := []struct {
+		name         string
+		data         []byte
+		wantMimeType string
+		wantDuration time.Duration
+		wantWidth    int
+		wantHeight   int
+		wantErr      bool
+	}{
+		{
+			name:         "MP4 landscape",
+			data:         testMP4("isom", mvhd, testTkhd(0, 1920, 1080, identityMatrix), false),
+			wantMimeType: "video/mp4",
+			wantDuration: 45 * time.Second,
+			wantWidth:    1920,
+			wantHeight:   1080,
+		},
+		{
+			name:         "MP4 with moov after media data",
+			data:         testMP4("mp42", mvhd, testTkhd(0, 1280, 720, identityMatrix), true),
+			wantMimeType: "video/mp4",
+			wantDuration: 45 * time.Second,
+			wantWidth:    1280,
+			wantHeight:   720,
+		},
+		{
+			name:         "MOV portrait recording rotated 90 degrees",
+			data:         testMP4("qt  ", mvhd, testTkhd(0, 1920, 1080, rotate90Matrix), false),
+			wantMimeType: "video/quicktime",
+			wantDuration: 45 * time.Second,
+			wantWidth:    1080,
+			wantHeight:   1920,
+		},
+		{
+			name:         "legacy QuickTime movie without ftyp",
+			data:         testBox("moov", testBox("mvhd", mvhd), testTrak("vide", testTkhd(0, 640, 480, identityMatrix))),
+			wantMimeType: "video/quicktime",
+			wantDuration: 45 * time.Second,
+			wantWidth:    640,
+			wantHeight:   480,
+		},
+		{
+			name:         "MP4 with version 1 headers",
+			data:         testMP4("isom", testMvhd(1, 90000, 90000*200), testTkhd(1, 640, 480, identityMatrix), false),
+			wantMimeType: "video/mp4",
+			wantDuration: 200 * time.Second,
+			wantWidth:    640,
+			wantHeight:   480,
+		},
+		{
+			name:         "MP4 with unknown duration",
+			data:         testMP4("isom", testMvhd(0, 1000, math.MaxUint32), testTkhd(0, 640, 480, identityMatrix), false),
+			wantMimeType: "video/mp4",
+			wantWidth:    640,
+			wantHeight:   480,
+		},
+		{
+			name:    "MP4 without video track",
+			data:    testMP4("isom", mvhd, nil, false),
+			wantErr: true,
+		},
+		{
+			name:         "WebM pixel size",
+			data:         testWebM("webm", 61500, 1280, 720, 0, 0),
+			wantMimeType: "video/webm",
+			wantDuration: 61500 * time.Millisecond,
+			wantWidth:    1280,
+			wantHeight:   720,
+		},
+		{
+			name:         "WebM display size without duration",
+			data:         testWebM("webm", 0, 1440, 1080, 1920, 1080),
+			wantMimeType: "video/webm",
+			wantWidth:    1920,
+			wantHeight:   1080,
+		},
+		{
+			name:         "Matroska",
+			data:         testWebM("matroska", 1000, 1280, 720, 0, 0),
+			wantMimeType: "video/x-matroska",
+			wantDuration: time.Second,
+			wantWidth:    1280,
+			wantHeight:   720,
+		},
+		{
+			name:    "unknown container",
+			data:    []byte("RIFF\x00\x00\x00\x00AVI LIST"),
+			wantErr: true,
+		},
+		{
+			name:    "empty file",
+			data:    make([]byte, 1024),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			meta, err := probeVideo(bytes.NewReader(tc.data), int64(len(tc.data)))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("probeVideo() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if meta.MimeType != tc.wantMimeType {
+				t.Errorf("probeVideo() mimeType = %s, want %s", meta.MimeType, tc.wantMimeType)
+			}
+			if meta.Duration != tc.wantDuration {
+				t.Errorf("probeVideo() duration = %s, want %s", meta.Duration, tc.wantDuration)
+			}
+			if meta.Width != tc.wantWidth || meta.Height != tc.wantHeight {
+				t.Errorf("probeVideo() = %dx%d, want %dx%d", meta.Width, meta.Height, tc.wantWidth, tc.wantHeight)
+			}
+		})
+	}
+}
+
+func TestValidateVideoContent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tempDir, err := os.MkdirTemp("", "video-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name            string
+		filename        string
+		data            []byte
+		maxDuration     time.Duration
+		wantMimeType    string
+		wantAspectRatio *AspectRatio
+		wantErr         string
+	}{
+		{
+			name:            "MP4 within duration limit",
+			filename:        "demo.mp4",
+			data:            testVideo(90 * time.Second),
+			maxDuration:     maxVideoDuration,
+			wantMimeType:    "video/mp4",
+			wantAspectRatio: &AspectRatio{Width: 1280, Height: 720},
+		},
+		{
+			name:            "portrait MOV with moov at the end",
+			filename:        "portrait.mov",
+			data:            testMP4("qt  ", testMvhd(0, 600, 6000), testTkhd(0, 1920, 1080, rotate90Matrix), true),
+			maxDuration:     maxVideoDuration,
+			wantMimeType:    "video/quicktime",
+			wantAspectRatio: &AspectRatio{Width: 1080, Height: 1920},
+		},
+		{
+			name:            "MP4 content with MOV extension",
+			filename:        "export.mov",
+			data:            testVideo(10 * time.Second),
+			maxDuration:     maxVideoDuration,
+			wantMimeType:    "video/mp4",
+			wantAspectRatio: &AspectRatio{Width: 1280, Height: 720},
+		},
+		{
+			name:        "longer than service maximum",
+			filename:    "talk.mp4",
+			data:        testVideo(10 * time.Minute),
+			maxDuration: maxVideoDuration,
+			wantErr:     "exceeds maximum duration of 3m0s (got 10m0s)",
+		},
+		{
+			name:        "longer than configured limit",
+			filename:    "demo.mp4",
+			data:        testVideo(90 * time.Second),
+			maxDuration: time.Minute,
+			wantErr:     "exceeds maximum duration of 1m0s",
+		},
+		{
+			name:        "corrupt file",
+			filename:    "corrupt.mp4",
+			data:        make([]byte, 4096),
+			maxDuration: maxVideoDuration,
+			wantErr:     "not a valid MP4, MOV, or WebM file",
+		},
+		{
+			name:        "WebM content with MP4 extension",
+			filename:    "clip.mp4",
+			data:        testWebM("webm", 1000, 1280, 720, 0, 0),
+			maxDuration: maxVideoDuration,
+			wantErr:     "does not match its extension",
+		},
+		{
+			name:        "Matroska renamed to WebM",
+			filename:    "clip.webm",
+			data:        testWebM("matroska", 1000, 1280, 720, 0, 0),
+			maxDuration: maxVideoDuration,
+			wantErr:     "unsupported video format video/x-matroska",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			videoPath := filepath.Join(tempDir, tc.filename)
+			if err := os.WriteFile(videoPath, tc.data, 0644); err != nil {
+				t.Fatalf("Failed to write test video: %v", err)
+			}
+
+			source, err := openVideoSource(videoPath, maxVideoSize, VideoOptions{}, logger)
+			if err != nil {
+				t.Fatalf("openVideoSource() unexpected error = %v", err)
+			}
+			defer source.Close()
+
+			meta, err := validateVideoContent(source, tc.maxDuration, logger)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("validateVideoContent() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateVideoContent() unexpected error = %v", err)
+			}
+
+			if meta.MimeType != tc.wantMimeType {
+				t.Errorf("validateVideoContent() mimeType = %s, want %s", meta.MimeType, tc.wantMimeType)
+			}
+			if ar := videoAspectRatio(meta); ar == nil || *ar != *tc.wantAspectRatio {
+				t.Errorf("videoAspectRatio() = %v, want %v", ar, tc.wantAspectRatio)
+			}
+
+			// Probing must not move the read position used for streaming the upload.
+			data, err := io.ReadAll(source.file)
+			if err != nil || int64(len(data)) != source.size {
+				t.Errorf("read %d bytes after probing, want %d (err %v)", len(data), source.size, err)
+			}
+		})
+	}
+}
+
+func TestVideoMaxDuration(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		limit time.Duration
+		want  time.Duration
+	}{
+		{limit: 0, want: maxVideoDuration},
+		{limit: 30 * time.Second, want: 30 * time.Second},
+		{limit: 10 * time.Minute, want: maxVideoDuration},
+	}
+
+	for _, tc := range tests {
+		if got := videoMaxDuration(VideoOptions{MaxDuration: tc.limit}, logger); got != tc.want {
+			t.Errorf("videoMaxDuration(%s) = %s, want %s", tc.limit, got, tc.want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ module github.com/cbrgm/bluesky-github-action
 
 go 1.22.2
 
-require github.com/alexflint/go-arg v1.6.1
+require (
+	github.com/alexflint/go-arg v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require github.com/alexflint/go-scalar v1.2.0 // indirect